@@ -1,13 +1,126 @@
 // Package board contains types and utils related to a pathfinding board
 package board
 
-// Tile holds information useful to the pathfinding algorithm
+import (
+	"errors"
+	"strings"
+
+	"github.com/adrienlucbert/gofeur/pkg"
+)
+
+// Direction is a set of directions a tile can be entered from
+type Direction uint8
+
+const (
+	// North is the direction of decreasing y
+	North Direction = 1 << iota
+	// East is the direction of increasing x
+	East
+	// South is the direction of increasing y
+	South
+	// West is the direction of decreasing x
+	West
+	// AnyDirection doesn't restrict moves
+	AnyDirection Direction = 0
+)
+
+var directionLetters = []struct {
+	direction Direction
+	letter    rune
+}{{North, 'N'}, {East, 'E'}, {South, 'S'}, {West, 'W'}}
+
+var errInvalidDirection = errors.New("invalid direction")
+
+// ParseDirection parses a set of directions written as a combination of the
+// N, E, S and W letters, or * for any direction
+func ParseDirection(str string) (Direction, error) {
+	if str == "*" {
+		return AnyDirection, nil
+	}
+	var direction Direction
+	for _, r := range strings.ToUpper(str) {
+		found := false
+		for _, it := range directionLetters {
+			if it.letter == r {
+				direction |= it.direction
+				found = true
+			}
+		}
+		if !found {
+			return AnyDirection, errInvalidDirection
+		}
+	}
+	if direction == AnyDirection {
+		return AnyDirection, errInvalidDirection
+	}
+	return direction, nil
+}
+
+func (d Direction) String() string {
+	if d == AnyDirection {
+		return "*"
+	}
+	var b strings.Builder
+	for _, it := range directionLetters {
+		if d&it.direction != 0 {
+			b.WriteRune(it.letter)
+		}
+	}
+	return b.String()
+}
+
+// DirectionOf returns the directions a move goes towards, 2 of them for
+// diagonal moves
+func DirectionOf(move pkg.Vector) Direction {
+	var direction Direction
+	switch {
+	case move.Y < 0:
+		direction |= North
+	case move.Y > 0:
+		direction |= South
+	}
+	switch {
+	case move.X > 0:
+		direction |= East
+	case move.X < 0:
+		direction |= West
+	}
+	return direction
+}
+
+// Tile holds information useful to the pathfinding algorithm. Cost is the
+// number of rounds it takes to enter the tile, 0 meaning 1, and Directions
+// restricts the moves that can enter the tile, if not AnyDirection. Obstacle
+// tiles are permanently blocked.
 type Tile struct {
-	Blocked   bool
-	DebugChar rune
+	Blocked    bool
+	DebugChar  rune
+	Cost       uint
+	Directions Direction
+	Obstacle   bool
+}
+
+// EnterCost returns the cost of entering the tile
+func (t Tile) EnterCost() uint {
+	if t.Cost == 0 {
+		return 1
+	}
+	return t.Cost
+}
+
+// Allows returns whether the tile can be entered with the given move
+func (t Tile) Allows(move pkg.Vector) bool {
+	if t.Directions == AnyDirection {
+		return true
+	}
+	direction := DirectionOf(move)
+	return direction != AnyDirection && t.Directions&direction == direction
 }
 
 func (t Tile) String() string {
+	if t.Obstacle {
+		return "#"
+	}
 	if t.Blocked {
 		if t.DebugChar != 0 {
 			return string(t.DebugChar)
@@ -49,11 +162,21 @@ func (b *Board) At(x uint, y uint) *Tile {
 	return &(*b)[y][x]
 }
 
-// Clear resets all tiles Blocked property to false
+// Clone returns a copy of the board, which tiles can be changed without
+// affecting the original
+func (b *Board) Clone() Board {
+	clone := make(Board, len(*b))
+	for i, row := range *b {
+		clone[i] = append([]Tile{}, row...)
+	}
+	return clone
+}
+
+// Clear resets all tiles Blocked property to false, except for obstacles
 func (b *Board) Clear() {
 	for y := uint(0); y < b.Height(); y++ {
 		for x := uint(0); x < b.Width(); x++ {
-			b.At(x, y).Blocked = false
+			b.At(x, y).Blocked = b.At(x, y).Obstacle
 		}
 	}
 }
@@ -3,6 +3,7 @@ package board
 import (
 	"testing"
 
+	"github.com/adrienlucbert/gofeur/pkg"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -30,6 +31,15 @@ func TestBoardAt(t *testing.T) {
 	assert.Equal(t, b.String(), "· · # \n· # · \n")
 }
 
+func TestBoardClone(t *testing.T) {
+	b := New(3, 2)
+	b.At(1, 1).Blocked = true
+	clone := b.Clone()
+	assert.Equal(t, b, clone)
+	clone.At(2, 0).Blocked = true
+	assert.False(t, b.At(2, 0).Blocked)
+}
+
 func TestBoardIsInBounds(t *testing.T) {
 	b := New(3, 2)
 	assert.False(t, b.IsInBounds(13, 37))
@@ -38,3 +48,44 @@ func TestBoardIsInBounds(t *testing.T) {
 	assert.True(t, b.IsInBounds(0, 0))
 	assert.True(t, b.IsInBounds(2, 1))
 }
+
+func TestParseDirection(t *testing.T) {
+	direction, err := ParseDirection("*")
+	assert.Nil(t, err)
+	assert.Equal(t, AnyDirection, direction)
+	direction, err = ParseDirection("ne")
+	assert.Nil(t, err)
+	assert.Equal(t, North|East, direction)
+	assert.Equal(t, "NE", direction.String())
+	_, err = ParseDirection("X")
+	assert.NotNil(t, err)
+	_, err = ParseDirection("")
+	assert.NotNil(t, err)
+}
+
+func TestTileAllows(t *testing.T) {
+	tile := Tile{}
+	assert.True(t, tile.Allows(pkg.Vector{X: -1}))
+	assert.Equal(t, uint(1), tile.EnterCost())
+
+	tile = Tile{Cost: 3, Directions: East | South}
+	assert.Equal(t, uint(3), tile.EnterCost())
+	assert.True(t, tile.Allows(pkg.Vector{X: 1}))
+	assert.True(t, tile.Allows(pkg.Vector{Y: 1}))
+	assert.True(t, tile.Allows(pkg.Vector{X: 1, Y: 1}))
+	assert.False(t, tile.Allows(pkg.Vector{X: -1}))
+	assert.False(t, tile.Allows(pkg.Vector{X: 1, Y: -1}))
+}
+
+func TestBoardClearKeepsObstacles(t *testing.T) {
+	b := New(3, 1)
+	b.At(0, 0).Blocked = true
+	b.At(0, 0).DebugChar = 'L'
+	b.At(2, 0).Blocked = true
+	b.At(2, 0).Obstacle = true
+	assert.Equal(t, "L · # \n", b.String())
+	b.Clear()
+	assert.False(t, b.At(0, 0).Blocked)
+	assert.True(t, b.At(2, 0).Blocked)
+	assert.Equal(t, "· · # \n", b.String())
+}
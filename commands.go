@@ -0,0 +1,251 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/adrienlucbert/gofeur/parsing"
+	"github.com/adrienlucbert/gofeur/scenario"
+	"github.com/adrienlucbert/gofeur/trace"
+	"github.com/adrienlucbert/gofeur/ui"
+)
+
+// commands maps subcommand names to their implementation, which receives the
+// arguments following the subcommand name
+var commands = map[string]func(args []string) error{
+	"fmt":      formatCommand,
+	"generate": generateCommand,
+	"lint":     lintCommand,
+	"replay":   replayCommand,
+}
+
+// formatCommand rewrites map files in their canonical form, printing them, or
+// writing them back in place
+func formatCommand(args []string) error {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	formatName := flags.String("format", parsing.AutoFormat.String(), "Map files format (auto, text, json, yaml), auto picking it from the file extension")
+	outputName := flags.String("to", parsing.AutoFormat.String(), "Output format (auto, text, json, yaml), auto keeping the input format")
+	write := flags.Bool("w", false, "Write the result to the map files instead of printing it")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gofeur fmt [flags] file... (- for the standard input)")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	format, err := parsing.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	output, err := parsing.ParseFormat(*outputName)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return nil
+	}
+
+	for _, file := range flags.Args() {
+		if file == stdinFilename && *write {
+			return errWriteStdin
+		}
+		simul, inputFormat, err := readMapFile(file, format)
+		if err != nil {
+			return err
+		}
+		outputFormat := output
+		if outputFormat == parsing.AutoFormat {
+			outputFormat = inputFormat
+		}
+
+		var formatted bytes.Buffer
+		if err := parsing.Encode(&formatted, simul, outputFormat); err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+
+		if *write {
+			err = os.WriteFile(file, formatted.Bytes(), 0o644)
+		} else {
+			_, err = os.Stdout.Write(formatted.Bytes())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var errWriteStdin = errors.New("can't write the standard input back")
+
+// lintCommand reports every error found in map files, as well as the problems
+// that would prevent their scenario from being completed
+func lintCommand(args []string) error {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	formatName := flags.String("format", parsing.AutoFormat.String(), "Map files format (auto, text, json, yaml), auto picking it from the file extension")
+	strict := flags.Bool("strict", false, "Fail on warnings too")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gofeur lint [flags] file... (- for the standard input)")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+
+	format, err := parsing.ParseFormat(*formatName)
+	if err != nil {
+		return err
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return nil
+	}
+
+	errorCount, warningCount := 0, 0
+	for _, file := range flags.Args() {
+		var diagnostics []parsing.Diagnostic
+		if file == stdinFilename {
+			_, diagnostics, err = parsing.LintReader(os.Stdin, "<stdin>", format)
+		} else {
+			_, diagnostics, err = parsing.LintInputFile(file, format)
+		}
+		if err != nil {
+			return err
+		}
+		for _, diagnostic := range diagnostics {
+			fmt.Println(diagnostic.String())
+			if diagnostic.Severity == parsing.Warning {
+				warningCount++
+			} else {
+				errorCount++
+			}
+		}
+	}
+
+	if errorCount > 0 || (*strict && warningCount > 0) {
+		return fmt.Errorf("%d error(s) and %d warning(s) found", errorCount, warningCount)
+	}
+	return nil
+}
+
+// readMapFile parses a map file, or the standard input if file is -, and
+// returns the format it was in
+func readMapFile(file string, format parsing.Format) (parsing.Simulation, parsing.Format, error) {
+	if file != stdinFilename {
+		if format == parsing.AutoFormat {
+			format = parsing.FormatOf(file)
+		}
+		simul, err := parsing.ParseInputFileAs(file, format)
+		return simul, format, err
+	}
+
+	data, err := io.ReadAll(os.Stdin)
+	if err != nil {
+		return parsing.Simulation{}, format, err
+	}
+	if format == parsing.AutoFormat {
+		format = parsing.DetectFormat(data)
+	}
+	simul, err := parsing.ParseReader(bytes.NewReader(data), format)
+	return simul, format, err
+}
+
+// generateCommand writes a random scenario, which is valid and can be
+// completed
+func generateCommand(args []string) error {
+	params := scenario.DefaultParams()
+	flags := flag.NewFlagSet("generate", flag.ExitOnError)
+	flags.Int64Var(&params.Seed, "seed", params.Seed, "Random seed, the same seed and parameters giving the same scenario")
+	width := flags.Uint("width", uint(params.Width), "Warehouse width")
+	length := flags.Uint("length", uint(params.Length), "Warehouse length")
+	cycles := flags.Uint("cycles", uint(params.Cycles), "Number of cycles")
+	parcels := flags.Uint("parcels", uint(params.Parcels), "Number of parcels")
+	flags.Var(&params.Colors, "colors", "Relative frequency of parcel colors, as `name=share` pairs such as yellow=2,blue=1 (default: all equally frequent)")
+	forklifts := flags.Uint("forklifts", uint(params.Forklifts), "Number of forklifts")
+	trucks := flags.Uint("trucks", uint(params.Trucks), "Number of trucks")
+	flags.Var(&params.Capacity, "capacity", "Range of the trucks' maximum weight, as `min-max`")
+	flags.Var(&params.Delay, "delay", "Range of the number of rounds trucks are away, as `min-max`")
+	flags.Float64Var(&params.ObstacleDensity, "obstacles", params.ObstacleDensity, "Share of the free cells covered by obstacles, from 0 to 1")
+	outputName := flags.String("to", parsing.AutoFormat.String(), "Output format (auto, text, json, yaml), auto picking it from the -o file extension")
+	outputFile := flags.String("o", "", "File to write the scenario to instead of printing it")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gofeur generate [flags]")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	params.Width, params.Length, params.Cycles = uint32(*width), uint32(*length), uint32(*cycles)
+	params.Parcels, params.Forklifts, params.Trucks = uint32(*parcels), uint32(*forklifts), uint32(*trucks)
+
+	output, err := parsing.ParseFormat(*outputName)
+	if err != nil {
+		return err
+	}
+	if output == parsing.AutoFormat {
+		output = parsing.FormatOf(*outputFile)
+	}
+
+	simul, err := scenario.Generate(params)
+	if err != nil {
+		return err
+	}
+	var generated bytes.Buffer
+	if err := parsing.Encode(&generated, simul, output); err != nil {
+		return err
+	}
+	if *outputFile != "" {
+		return os.WriteFile(*outputFile, generated.Bytes(), 0o644)
+	}
+	_, err = os.Stdout.Write(generated.Bytes())
+	return err
+}
+
+// replayFrameDuration is the time between two updates of the replay UI
+const replayFrameDuration = 20 * time.Millisecond
+
+// replayCommand displays a recorded trace in the UI, which rounds can be
+// played, stepped through and sought, until it's quit
+func replayCommand(args []string) error {
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	round := flags.Uint("seek", 0, "Round to show first")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: gofeur replay [flags] trace")
+		fmt.Fprintln(flags.Output(), "keys: space plays or pauses, n and p step, page down and up skip 100 rounds, home and end go to the first and last rounds, escape quits")
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return nil
+	}
+
+	recorded, err := trace.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer recorded.Close()
+	gofeur, err := recorded.Header.Simulation()
+	if err != nil {
+		return err
+	}
+	player, err := trace.NewPlayer(recorded.Header, recorded)
+	if err != nil {
+		return err
+	}
+	if player.Seek(*round); player.Err() != nil {
+		return player.Err()
+	}
+
+	layer := &ui.Layer{Gofeur: &gofeur, Replay: player}
+	layer.Attach()
+	lastUpdateTime := time.Now()
+	for layer.IsRunning() {
+		updateTime := time.Now()
+		layer.Update(updateTime.Sub(lastUpdateTime))
+		lastUpdateTime = updateTime
+		time.Sleep(replayFrameDuration)
+	}
+	layer.Detach()
+	return player.Err()
+}
@@ -4,6 +4,7 @@ package logger
 
 import (
 	"fmt"
+	"os"
 
 	"github.com/adrienlucbert/gofeur/config"
 )
@@ -42,7 +43,7 @@ func log(level logLevel, format string, a ...any) {
 	if !shouldLog(level) {
 		return
 	}
-	fmt.Printf(format, a...)
+	fmt.Fprintf(os.Stderr, format, a...)
 }
 
 // Debug logs a value if debug logs are enabled
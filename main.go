@@ -4,13 +4,17 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
+	"strings"
 	"time"
 
 	"github.com/adrienlucbert/gofeur/config"
 	"github.com/adrienlucbert/gofeur/logger"
+	"github.com/adrienlucbert/gofeur/output"
 	"github.com/adrienlucbert/gofeur/parsing"
 	"github.com/adrienlucbert/gofeur/pkg"
 	"github.com/adrienlucbert/gofeur/simulation"
+	"github.com/adrienlucbert/gofeur/trace"
 	"github.com/adrienlucbert/gofeur/ui"
 )
 
@@ -31,9 +35,27 @@ func main() {
 		}
 	}()
 
-	filename := flag.String("filename", "", "Map file path")
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			if err := command(os.Args[2:]); err != nil {
+				println(gofeurError{err: err.Error()}.Error())
+				os.Exit(1)
+			}
+			return
+		}
+	}
+
+	filename := flag.String("filename", "", "Map file path, - to read it from the standard input")
 	displayUI := flag.Bool("ui", false, "Display UI")
 	logLevel := flag.String("log-level", "Info", "Log level (Debug, Info, Warn, Error, None)")
+	reportFormat := flag.String("report", "", "Print an end-of-game report (text, json)")
+	outputFile := flag.String("output", output.Stdout, fmt.Sprintf("Where to write the simulation output: a file, %s for the standard output, or %s to discard it (discarded with -ui unless it's a file)", output.Stdout, output.Discard))
+	recordFile := flag.String("record", "", "Record a trace of the simulation to the given file")
+	strategyName := flag.String("strategy", simulation.DefaultStrategy, fmt.Sprintf("Simulation strategy (%s)", strings.Join(simulation.StrategyNames(), ", ")))
+	deadlockRounds := flag.Uint("deadlock-rounds", 0, fmt.Sprintf("Number of rounds without progress after which forklifts are considered stuck, such as %d (0 disables detection)", simulation.SuggestedDeadlockRounds))
+	deadlockRecovery := flag.String("deadlock-recovery", simulation.StopOnDeadlock.String(), "What to do when forklifts are stuck (stop, yield)")
+	diagnose := flag.Bool("diagnostics", false, "Print every error found in the map file instead of stopping at the first one")
+	formatName := flag.String("format", parsing.AutoFormat.String(), "Map file format (auto, text, json, yaml), auto picking it from the file extension")
 	flag.Parse()
 
 	if *filename == "" {
@@ -44,21 +66,53 @@ func main() {
 	config.Set("displayUI", displayUI)
 	logger.SetLogLevel(*logLevel)
 
-	gofeur, err := parsing.ParseInputFile(*filename)
+	format, err := parsing.ParseFormat(*formatName)
 	if err != nil {
 		println(gofeurError{err: err.Error()}.Error())
 		return
 	}
-	err = parsing.VerifySimulationValidity(gofeur)
+
+	gofeur, err := loadInputFile(*filename, format, *diagnose)
 	if err != nil {
 		println(gofeurError{err: err.Error()}.Error())
 		return
 	}
 
-	sim := simulation.New(&gofeur)
+	strategy, err := simulation.NewStrategy(*strategyName)
+	if err != nil {
+		println(gofeurError{err: err.Error()}.Error())
+		return
+	}
+	recovery, err := simulation.ParseDeadlockRecovery(*deadlockRecovery)
+	if err != nil {
+		println(gofeurError{err: err.Error()}.Error())
+		return
+	}
+	sim := simulation.New(&gofeur, strategy)
+	sim.DeadlockRounds = *deadlockRounds
+	sim.DeadlockRecovery = recovery
+	destination := *outputFile
+	// the output would be written over the UI, unless it goes to a file
+	if *displayUI && destination == output.Stdout {
+		destination = output.Discard
+	}
+	out, err := output.Open(destination)
+	if err != nil {
+		println(gofeurError{err: err.Error()}.Error())
+		return
+	}
+	sim.Subscribe(out)
 
 	layers := []pkg.Layer{
-		&simulation.Layer{Simulation: &sim},
+		&simulation.Layer{Simulation: &sim, ReportFormat: *reportFormat, ReportWriter: os.Stdout},
+	}
+	if *recordFile != "" {
+		recorder, err := trace.Create(*recordFile, &gofeur, &sim)
+		if err != nil {
+			println(gofeurError{err: err.Error()}.Error())
+			return
+		}
+		layers = append(layers, recorder)
 	}
 	if *displayUI {
 		layers = append(layers, &ui.Layer{Gofeur: &gofeur, Simulation: &sim})
@@ -78,4 +132,54 @@ func main() {
 	for _, layer := range layers {
 		layer.Detach()
 	}
+	if err := out.Close(); err != nil {
+		println(gofeurError{err: err.Error()}.Error())
+	}
+}
+
+// stdinFilename is the filename standing for the standard input
+const stdinFilename = "-"
+
+// loadInputFile parses and validates the map file, or the standard input if
+// filename is -. With diagnose, every error found is printed, instead of only
+// the first one.
+func loadInputFile(filename string, format parsing.Format, diagnose bool) (parsing.Simulation, error) {
+	if diagnose {
+		return diagnoseInputFile(filename, format)
+	}
+
+	var gofeur parsing.Simulation
+	var err error
+	if filename == stdinFilename {
+		gofeur, err = parsing.ParseReader(os.Stdin, format)
+	} else {
+		gofeur, err = parsing.ParseInputFileAs(filename, format)
+	}
+	if err != nil {
+		return gofeur, err
+	}
+	return gofeur, parsing.VerifySimulationValidity(gofeur)
+}
+
+// diagnoseInputFile prints every error found in the map file, or the standard
+// input if filename is -
+func diagnoseInputFile(filename string, format parsing.Format) (parsing.Simulation, error) {
+	var gofeur parsing.Simulation
+	var diagnostics []parsing.Diagnostic
+	var err error
+	if filename == stdinFilename {
+		gofeur, diagnostics, err = parsing.DiagnoseReader(os.Stdin, "<stdin>", format)
+	} else {
+		gofeur, diagnostics, err = parsing.DiagnoseInputFile(filename, format)
+	}
+	if err != nil {
+		return gofeur, err
+	}
+	for _, diagnostic := range diagnostics {
+		fmt.Fprintln(os.Stderr, diagnostic.String())
+	}
+	if len(diagnostics) > 0 {
+		return gofeur, fmt.Errorf("%d error(s) found in the map file", len(diagnostics))
+	}
+	return gofeur, nil
 }
@@ -0,0 +1,86 @@
+// Package output writes the official output of a simulation, round by round,
+// apart from diagnostic logs
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/adrienlucbert/gofeur/simulation"
+)
+
+const (
+	// Stdout is the destination standing for the standard output
+	Stdout = "-"
+	// Discard is the destination standing for no output at all
+	Discard = "none"
+)
+
+// Writer writes the output of a simulation, as an observer of its events:
+// the number of each round, the action of each forklift and the state of each
+// truck, then an emoji telling how the simulation ended. It must be closed
+// once the simulation is over.
+type Writer struct {
+	buffer *bufio.Writer
+	closer io.Closer
+	err    error
+}
+
+// New returns a writer writing the output to w
+func New(w io.Writer) *Writer {
+	return &Writer{buffer: bufio.NewWriter(w)}
+}
+
+type outputFileError struct {
+	file string
+	err  error
+}
+
+func (err outputFileError) Error() string {
+	return fmt.Sprintf("failed to create the output file %s: %s", err.file, err.err.Error())
+}
+
+// Open returns a writer writing the output to the given destination: Stdout,
+// Discard, or the path of a file, which is created or truncated
+func Open(destination string) (*Writer, error) {
+	switch destination {
+	case Stdout:
+		return New(os.Stdout), nil
+	case Discard:
+		return New(io.Discard), nil
+	}
+	file, err := os.Create(destination)
+	if err != nil {
+		return nil, outputFileError{file: destination, err: err}
+	}
+	writer := New(file)
+	writer.closer = file
+	return writer, nil
+}
+
+// OnEvent implements simulation.Observer
+func (w *Writer) OnEvent(event simulation.Event) {
+	if w.err != nil {
+		return
+	}
+	_, w.err = fmt.Fprintln(w.buffer, event.String())
+	if event.Kind == simulation.EndEvent && w.err == nil {
+		w.err = w.buffer.Flush()
+	}
+}
+
+// Close flushes the output, closes its file, if any, and returns the first
+// error met while writing
+func (w *Writer) Close() error {
+	if w.err == nil {
+		w.err = w.buffer.Flush()
+	}
+	if w.closer != nil {
+		if err := w.closer.Close(); w.err == nil {
+			w.err = err
+		}
+	}
+	return w.err
+}
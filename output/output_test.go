@@ -0,0 +1,84 @@
+package output
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adrienlucbert/gofeur/config"
+	"github.com/adrienlucbert/gofeur/parsing"
+	"github.com/adrienlucbert/gofeur/simulation"
+	"github.com/stretchr/testify/assert"
+)
+
+var update = flag.Bool("update", false, "Update the golden files")
+
+func run(t *testing.T, file string, writer *Writer) {
+	t.Helper()
+	config.Set("logLevel", "None")
+	gofeur, err := parsing.ParseInputFile(file)
+	assert.Nil(t, err)
+	sim := simulation.New(&gofeur, nil)
+	sim.Subscribe(writer)
+	for sim.Status == simulation.Idle || sim.IsRunning() {
+		sim.Step()
+	}
+}
+
+func TestGoldenFiles(t *testing.T) {
+	scenarios, err := filepath.Glob("testdata/*.txt")
+	assert.Nil(t, err)
+	assert.NotEmpty(t, scenarios)
+
+	for _, scenario := range scenarios {
+		var output bytes.Buffer
+		writer := New(&output)
+		run(t, scenario, writer)
+		assert.Nil(t, writer.Close())
+
+		golden := strings.TrimSuffix(scenario, ".txt") + ".golden"
+		if *update {
+			assert.Nil(t, os.WriteFile(golden, output.Bytes(), 0o644))
+		}
+		expected, err := os.ReadFile(golden)
+		assert.Nil(t, err)
+		assert.Equal(t, string(expected), output.String(), scenario)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "output.txt")
+	writer, err := Open(file)
+	assert.Nil(t, err)
+	run(t, "testdata/basic.txt", writer)
+	assert.Nil(t, writer.Close())
+
+	written, err := os.ReadFile(file)
+	assert.Nil(t, err)
+	expected, err := os.ReadFile("testdata/basic.golden")
+	assert.Nil(t, err)
+	assert.Equal(t, string(expected), string(written))
+
+	writer, err = Open(Discard)
+	assert.Nil(t, err)
+	run(t, "testdata/basic.txt", writer)
+	assert.Nil(t, writer.Close())
+
+	_, err = Open(filepath.Join(t.TempDir(), "missing", "output.txt"))
+	assert.NotNil(t, err)
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, os.ErrClosed
+}
+
+func TestWriteError(t *testing.T) {
+	writer := New(failingWriter{})
+	run(t, "testdata/basic.txt", writer)
+	assert.ErrorIs(t, writer.Close(), os.ErrClosed)
+}
@@ -0,0 +1,223 @@
+package parsing
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Severity tells whether a diagnostic prevents the simulation from running
+type Severity int
+
+const (
+	// Error diagnostics make the input file invalid, or the scenario
+	// impossible to complete
+	Error Severity = iota
+	// Warning diagnostics point at likely mistakes, which don't prevent the
+	// scenario from being completed
+	Warning
+)
+
+func (severity Severity) String() string {
+	if severity == Warning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Diagnostic describes an error found in an input file. Line and Column are
+// 1-based, Column being the index of the faulty token in its line, or 1 when
+// the whole line is at fault. Both are 0 when the location is unknown, as in
+// JSON and YAML documents. Code is a stable identifier of the kind of error.
+type Diagnostic struct {
+	File     string
+	Line     uint32
+	Column   uint32
+	Severity Severity
+	Code     string
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	message := d.Message
+	if d.Severity == Warning {
+		message = "warning: " + message
+	}
+	if d.Line == 0 {
+		return fmt.Sprintf("%s: %s [%s]", d.File, message, d.Code)
+	}
+	return fmt.Sprintf("%s:%d:%d: %s [%s]", d.File, d.Line, d.Column, message, d.Code)
+}
+
+// Codes of the errors that aren't about a single token
+const (
+	codeInvalidLine           = "P100"
+	codeHeaderBeforeWarehouse = "P101"
+	codeUnknownSection        = "P102"
+	codeReadError             = "P103"
+	codeInvalidDocument       = "P104"
+	codeClassesAfterParcels   = "P105"
+
+	codeNoForklift         = "V001"
+	codeNoTruck            = "V002"
+	codeTooSmallWarehouse  = "V003"
+	codeTruckNotOnSide     = "V004"
+	codeOutOfBound         = "V005"
+	codeStackedEntities    = "V006"
+	codeDuplicatedName     = "V007"
+	codeTileOutOfBound     = "V008"
+	codeDuplicatedTile     = "V009"
+	codeObstacleOutOfBound = "V010"
+	codeEntityOnObstacle   = "V011"
+	codeDuplicatedClass    = "V012"
+
+	codeTooHeavyParcel      = "L001"
+	codeUnreachableParcel   = "L002"
+	codeUndeliverableParcel = "L003"
+	codeUnreachableTruck    = "L004"
+	codeIdleForklift        = "L005"
+)
+
+// Code returns the stable code of the error kind
+func (kind parserErrorKind) Code() string {
+	switch kind {
+	case invalidTokenLength:
+		return "P001"
+	case invalidNumberOfTokens:
+		return "P002"
+	case invalidUnsignedInteger:
+		return "P003"
+	case invalidCycleNumber:
+		return "P004"
+	case invalidWeight:
+		return "P005"
+	case invalidCost:
+		return "P006"
+	case invalidDirection:
+		return "P007"
+	case invalidSize:
+		return "P008"
+	case invalidGlyph:
+		return "P009"
+	default:
+		panic("Unreachable")
+	}
+}
+
+func (err inputError) code() string {
+	switch inner := err.err.(type) {
+	case parserError:
+		return inner.Kind().Code()
+	case unknownSectionError:
+		return codeUnknownSection
+	}
+	switch err.err {
+	case errInvalidLine:
+		return codeInvalidLine
+	case errHeaderBeforeWarehouse:
+		return codeHeaderBeforeWarehouse
+	case errClassesAfterParcels:
+		return codeClassesAfterParcels
+	default:
+		return codeReadError
+	}
+}
+
+// sourceKey identifies something declared in an input file
+type sourceKey struct {
+	kind  string
+	name  string
+	coord coordinate
+}
+
+var warehouseSourceKey = sourceKey{kind: "warehouse"}
+
+func entitySourceKey(e entity) sourceKey {
+	return sourceKey{kind: e.kind(), name: string(e.stringerName()), coord: e.coord()}
+}
+
+// sourceMap maps what was declared in an input file to the line it was
+// declared on
+type sourceMap map[sourceKey]uint32
+
+// DiagnoseInputFile parses and validates a simulation input file in the given
+// format, and returns every error found instead of stopping at the first one.
+// The returned error is only set if the file couldn't be read.
+func DiagnoseInputFile(file string, format Format) (Simulation, []Diagnostic, error) {
+	handle, err := os.Open(file)
+	if err != nil {
+		return Simulation{}, nil, inputFileOpenError{file: file, err: err}
+	}
+	defer handle.Close()
+
+	if format == AutoFormat {
+		format = FormatOf(file)
+	}
+	return DiagnoseReader(handle, file, format)
+}
+
+// DiagnoseReader parses and validates a simulation from a reader, and returns
+// every error found, name being the file name used in diagnostics. With
+// AutoFormat, the format is guessed from the content, as told by
+// DetectFormat, and the returned error is set if the content couldn't be read
+// to guess it. Other read errors are reported as diagnostics.
+func DiagnoseReader(reader io.Reader, name string, format Format) (Simulation, []Diagnostic, error) {
+	simul, _, diagnostics, err := diagnose(reader, name, format)
+	return simul, diagnostics, err
+}
+
+// diagnose is DiagnoseReader, which also returns the lines things were
+// declared on, or a nil map for JSON and YAML documents
+func diagnose(reader io.Reader, name string, format Format) (Simulation, sourceMap, []Diagnostic, error) {
+	if format == AutoFormat {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return Simulation{}, nil, nil, err
+		}
+		reader = bytes.NewReader(data)
+		format = DetectFormat(data)
+	}
+	if format != TextFormat {
+		simul, diagnostics := diagnoseDocument(name, reader, format)
+		return simul, nil, diagnostics, nil
+	}
+
+	p := newParser(true)
+	p.parse(reader)
+
+	diagnostics := make([]Diagnostic, 0, len(p.errors))
+	for _, err := range p.errors {
+		diagnostics = append(diagnostics, Diagnostic{
+			File:    name,
+			Line:    err.line,
+			Column:  err.column,
+			Code:    err.code(),
+			Message: fmt.Sprintf("when parsing a %s: %s", err.section, err.err.Error()),
+		})
+	}
+	for _, v := range validate(p.simul) {
+		diagnostics = append(diagnostics, v.diagnostic(name, p.sources))
+	}
+	return p.simul, p.sources, diagnostics, nil
+}
+
+// diagnoseDocument parses and validates a JSON or YAML document. Its
+// diagnostics don't have a line, but their message tells which element of
+// the document they are about.
+func diagnoseDocument(file string, reader io.Reader, format Format) (Simulation, []Diagnostic) {
+	doc, err := decodeDocument(reader, format)
+	if err != nil {
+		return Simulation{}, []Diagnostic{{File: file, Code: codeInvalidDocument, Message: err.Error()}}
+	}
+
+	simul, errs := doc.simulation()
+	diagnostics := make([]Diagnostic, 0, len(errs))
+	for _, err := range errs {
+		diagnostics = append(diagnostics, Diagnostic{File: file, Code: err.Kind().Code(), Message: err.Error()})
+	}
+	for _, v := range validate(simul) {
+		diagnostics = append(diagnostics, v.diagnostic(file, nil))
+	}
+	return simul, diagnostics
+}
@@ -0,0 +1,116 @@
+package parsing
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeInputFile(t *testing.T, lines []string) string {
+	file := filepath.Join(t.TempDir(), "map.txt")
+	err := os.WriteFile(file, []byte(strings.Join(lines, "\n")), 0o600)
+	assert.Nil(t, err)
+	return file
+}
+
+func TestDiagnoseInputFile(t *testing.T) {
+	file := writeInputFile(t, []string{
+		"5 5 100",
+		"parcel 1 a green",
+		"[pallets]",
+		"[forklifts]",
+		"forklift_a 2 2",
+		"forklift_b 1 1 extra",
+		"[trucks]",
+		"truck 2 3 1000 3",
+		"[obstacles]",
+		"2 2",
+		"9 9",
+	})
+
+	_, diagnostics, err := DiagnoseInputFile(file, AutoFormat)
+	assert.Nil(t, err)
+
+	type position struct {
+		line, column uint32
+		code         string
+	}
+	positions := make([]position, 0, len(diagnostics))
+	for _, diagnostic := range diagnostics {
+		assert.Equal(t, file, diagnostic.File)
+		positions = append(positions, position{diagnostic.Line, diagnostic.Column, diagnostic.Code})
+	}
+	assert.Equal(t, []position{
+		{2, 3, invalidUnsignedInteger.Code()},
+		{3, 1, codeUnknownSection},
+		{6, 1, invalidNumberOfTokens.Code()},
+		{8, 1, codeTruckNotOnSide},
+		{11, 1, codeObstacleOutOfBound},
+		{5, 1, codeEntityOnObstacle},
+	}, positions)
+}
+
+func TestDiagnoseValidInputFile(t *testing.T) {
+	file := writeInputFile(t, []string{
+		"5 5 100",
+		"parcel 1 1 green",
+		"forklift 0 2",
+		"truck 4 4 1000 3",
+	})
+
+	simul, diagnostics, err := DiagnoseInputFile(file, AutoFormat)
+	assert.Nil(t, err)
+	assert.Empty(t, diagnostics)
+	assert.Len(t, simul.Warehouse.Trucks, 1)
+
+	_, _, err = DiagnoseInputFile(filepath.Join(t.TempDir(), "missing.txt"), AutoFormat)
+	assert.NotNil(t, err)
+}
+
+func TestDiagnosticString(t *testing.T) {
+	diagnostic := Diagnostic{File: "map.txt", Line: 3, Column: 2, Code: "P003", Message: "invalid unsigned integer"}
+	assert.Equal(t, "map.txt:3:2: invalid unsigned integer [P003]", diagnostic.String())
+}
+
+func TestDiagnoseDocument(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "map.json")
+	err := os.WriteFile(file, []byte(`{
+		"width": 5, "length": 5, "cycles": 100,
+		"parcels": [{"name": "parcel", "x": 1, "y": 1, "color": "red"}],
+		"forklifts": [{"name": "forklift", "x": 0, "y": 2}],
+		"trucks": [{"name": "truck", "x": 2, "y": 2, "max_weight": 1000, "available": 3}]
+	}`), 0o600)
+	assert.Nil(t, err)
+
+	_, diagnostics, err := DiagnoseInputFile(file, AutoFormat)
+	assert.Nil(t, err)
+	codes := []string{}
+	for _, diagnostic := range diagnostics {
+		codes = append(codes, diagnostic.Code)
+	}
+	assert.Equal(t, []string{invalidWeight.Code(), codeTruckNotOnSide}, codes)
+	assert.Equal(t, file+": parcels[0]: invalid weight for field: color (found: 'red') [P005]", diagnostics[0].String())
+}
+
+func TestDiagnoseReader(t *testing.T) {
+	_, diagnostics, err := DiagnoseReader(strings.NewReader("5 5 100\nparcel 1 a green"), "<stdin>", AutoFormat)
+	assert.Nil(t, err)
+	assert.Len(t, diagnostics, 3)
+	assert.Equal(t, "<stdin>:2:3: when parsing a parcel: invalid unsigned integer for field: y (found: 'a') [P003]", diagnostics[0].String())
+
+	_, diagnostics, err = DiagnoseReader(strings.NewReader(`{"width": 5, "length": 5, "cycles": 100, "pallets": []}`), "<stdin>", AutoFormat)
+	assert.Nil(t, err)
+	assert.Len(t, diagnostics, 1)
+	assert.Equal(t, codeInvalidDocument, diagnostics[0].Code)
+
+	// a colon in a token doesn't make text input a YAML document
+	_, diagnostics, err = DiagnoseReader(strings.NewReader("5 5:3 100\nparcel 1 1 green"), "<stdin>", AutoFormat)
+	assert.Nil(t, err)
+	if assert.NotEmpty(t, diagnostics) {
+		assert.Equal(t, uint32(1), diagnostics[0].Line)
+		assert.True(t, strings.HasPrefix(diagnostics[0].Code, "P"), diagnostics[0].String())
+	}
+}
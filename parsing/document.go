@@ -0,0 +1,325 @@
+package parsing
+
+import (
+	// embed is needed to embed the JSON Schema
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format represents an input file format
+type Format int
+
+const (
+	// AutoFormat picks the format of an input file from its extension
+	AutoFormat Format = iota
+	// TextFormat is the space-separated format, one entity per line
+	TextFormat
+	// JSONFormat is a JSON document, as described by Schema
+	JSONFormat
+	// YAMLFormat is a YAML document, with the same structure as JSONFormat
+	YAMLFormat
+)
+
+func (format Format) String() string {
+	return map[Format]string{
+		AutoFormat: "auto",
+		TextFormat: "text",
+		JSONFormat: "json",
+		YAMLFormat: "yaml",
+	}[format]
+}
+
+type unknownFormatError struct {
+	name string
+}
+
+func (err unknownFormatError) Error() string {
+	return fmt.Sprintf("unknown input format '%s'", err.name)
+}
+
+// ParseFormat returns the format with the given name
+func ParseFormat(name string) (Format, error) {
+	for _, format := range []Format{AutoFormat, TextFormat, JSONFormat, YAMLFormat} {
+		if format.String() == strings.ToLower(name) {
+			return format, nil
+		}
+	}
+	return AutoFormat, unknownFormatError{name: name}
+}
+
+// FormatOf returns the format of a file from its extension: .json files are
+// JSON, .yaml and .yml files are YAML, and anything else is text
+func FormatOf(file string) Format {
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".json":
+		return JSONFormat
+	case ".yaml", ".yml":
+		return YAMLFormat
+	default:
+		return TextFormat
+	}
+}
+
+// Schema is the JSON Schema of JSON and YAML input files
+//
+//go:embed scenario.schema.json
+var Schema []byte
+
+// yamlKey matches the start of a YAML line holding a key: an identifier
+// followed by a colon, and a space or nothing
+var yamlKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_-]*:(\s|$)`)
+
+// DetectFormat guesses the format of an input from its first line that isn't
+// blank nor a comment: JSON documents start with {, YAML ones with --- or a
+// key: value pair, and anything else is text, even if it holds a colon
+func DetectFormat(data []byte) Format {
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(line, "{"):
+			return JSONFormat
+		case strings.HasPrefix(line, "---") || yamlKey.MatchString(line):
+			return YAMLFormat
+		default:
+			return TextFormat
+		}
+	}
+	return TextFormat
+}
+
+// document is the structure of JSON and YAML input files. Optional numbers
+// are pointers, so that an explicit zero can be told apart from an omitted
+// value.
+type document struct {
+	Width     uint32             `json:"width" yaml:"width"`
+	Length    uint32             `json:"length" yaml:"length"`
+	Cycles    uint32             `json:"cycles" yaml:"cycles"`
+	Parcels   []parcelDocument   `json:"parcels" yaml:"parcels"`
+	Forklifts []forkliftDocument `json:"forklifts" yaml:"forklifts"`
+	Trucks    []truckDocument    `json:"trucks" yaml:"trucks"`
+	Tiles     []tileDocument     `json:"tiles,omitempty" yaml:"tiles,omitempty"`
+	Obstacles []obstacleDocument `json:"obstacles,omitempty" yaml:"obstacles,omitempty"`
+	Classes   []classDocument    `json:"classes,omitempty" yaml:"classes,omitempty"`
+}
+
+type classDocument struct {
+	Name   string `json:"name" yaml:"name"`
+	Weight uint32 `json:"weight" yaml:"weight"`
+	Glyph  string `json:"glyph" yaml:"glyph"`
+	Color  string `json:"color" yaml:"color"`
+}
+
+type parcelDocument struct {
+	Name  string `json:"name" yaml:"name"`
+	X     uint32 `json:"x" yaml:"x"`
+	Y     uint32 `json:"y" yaml:"y"`
+	Color string `json:"color" yaml:"color"`
+}
+
+type forkliftDocument struct {
+	Name string `json:"name" yaml:"name"`
+	X    uint32 `json:"x" yaml:"x"`
+	Y    uint32 `json:"y" yaml:"y"`
+}
+
+type truckDocument struct {
+	Name      string `json:"name" yaml:"name"`
+	X         uint32 `json:"x" yaml:"x"`
+	Y         uint32 `json:"y" yaml:"y"`
+	MaxWeight uint32 `json:"max_weight" yaml:"max_weight"`
+	Available uint32 `json:"available" yaml:"available"`
+}
+
+type tileDocument struct {
+	X          uint32  `json:"x" yaml:"x"`
+	Y          uint32  `json:"y" yaml:"y"`
+	Cost       *uint32 `json:"cost,omitempty" yaml:"cost,omitempty"`
+	Directions string  `json:"directions,omitempty" yaml:"directions,omitempty"`
+}
+
+type obstacleDocument struct {
+	X      uint32  `json:"x" yaml:"x"`
+	Y      uint32  `json:"y" yaml:"y"`
+	Width  *uint32 `json:"width,omitempty" yaml:"width,omitempty"`
+	Length *uint32 `json:"length,omitempty" yaml:"length,omitempty"`
+}
+
+// documentError is an error about an element of a JSON or YAML document,
+// which path is formatted like parcels[2]
+type documentError struct {
+	path string
+	err  parserError
+}
+
+func (err documentError) Error() string {
+	return fmt.Sprintf("%s: %s", err.path, err.err.Error())
+}
+
+func (err documentError) Kind() parserErrorKind {
+	return err.err.Kind()
+}
+
+var errMultipleDocuments = errors.New("input file should hold a single document")
+
+// decodeDocument decodes a JSON or YAML document, rejecting unknown fields
+func decodeDocument(reader io.Reader, format Format) (document, error) {
+	var doc document
+	switch format {
+	case JSONFormat:
+		decoder := json.NewDecoder(reader)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&doc); err != nil {
+			return doc, err
+		}
+		if decoder.More() {
+			return doc, errMultipleDocuments
+		}
+	case YAMLFormat:
+		decoder := yaml.NewDecoder(reader)
+		decoder.KnownFields(true)
+		if err := decoder.Decode(&doc); err != nil {
+			return doc, err
+		}
+		var next yaml.Node
+		if decoder.Decode(&next) != io.EOF {
+			return doc, errMultipleDocuments
+		}
+	default:
+		panic("Unreachable: not a structured format")
+	}
+	return doc, nil
+}
+
+// simulation turns the document into a simulation. Every value goes through
+// the same token parsers as the text format, so that both formats accept the
+// same values. Elements that can't be parsed are skipped, and the errors are
+// returned in the order of the document.
+func (doc document) simulation() (Simulation, []documentError) {
+	errs := []documentError{}
+	fail := func(section string, i int, err parserError) {
+		path := section
+		if i >= 0 {
+			path = fmt.Sprintf("%s[%d]", section, i)
+		}
+		errs = append(errs, documentError{path: path, err: err})
+	}
+
+	simul, err := parseWarehouseSection(uints(doc.Width, doc.Length, doc.Cycles))
+	if err != nil {
+		fail("warehouse", -1, err)
+	}
+	warehouse := &simul.Warehouse
+
+	for i, c := range doc.Classes {
+		class, err := parseClass([]string{c.Name, uints(c.Weight)[0], c.Glyph, c.Color})
+		if err != nil {
+			fail("classes", i, err)
+			continue
+		}
+		warehouse.Classes = append(warehouse.Classes, class)
+	}
+	for i, p := range doc.Parcels {
+		parcel, err := parseParcel(append([]string{p.Name}, append(uints(p.X, p.Y), p.Color)...), warehouse.ParcelClasses())
+		if err != nil {
+			fail("parcels", i, err)
+			continue
+		}
+		warehouse.Parcels = append(warehouse.Parcels, parcel)
+	}
+	for i, f := range doc.Forklifts {
+		forklift, err := parseForklift(append([]string{f.Name}, uints(f.X, f.Y)...))
+		if err != nil {
+			fail("forklifts", i, err)
+			continue
+		}
+		warehouse.Forklifts = append(warehouse.Forklifts, forklift)
+	}
+	for i, t := range doc.Trucks {
+		truck, err := parseTruck(append([]string{t.Name}, uints(t.X, t.Y, t.MaxWeight, t.Available)...))
+		if err != nil {
+			fail("trucks", i, err)
+			continue
+		}
+		warehouse.Trucks = append(warehouse.Trucks, truck)
+	}
+	for i, t := range doc.Tiles {
+		cost := uint32(1)
+		if t.Cost != nil {
+			cost = *t.Cost
+		}
+		directions := t.Directions
+		if directions == "" {
+			directions = "*"
+		}
+		tile, err := parseTile(append(uints(t.X, t.Y, cost), directions))
+		if err != nil {
+			fail("tiles", i, err)
+			continue
+		}
+		warehouse.Tiles = append(warehouse.Tiles, tile)
+	}
+	for i, o := range doc.Obstacles {
+		tokens := uints(o.X, o.Y)
+		if o.Width != nil || o.Length != nil {
+			width, length := uint32(1), uint32(1)
+			if o.Width != nil {
+				width = *o.Width
+			}
+			if o.Length != nil {
+				length = *o.Length
+			}
+			tokens = append(tokens, fmt.Sprintf("%dx%d", width, length))
+		}
+		obstacle, err := parseObstacle(tokens)
+		if err != nil {
+			fail("obstacles", i, err)
+			continue
+		}
+		warehouse.Obstacles = append(warehouse.Obstacles, obstacle)
+	}
+
+	return simul, errs
+}
+
+// uints formats unsigned integers as tokens
+func uints(values ...uint32) []string {
+	tokens := make([]string, 0, len(values))
+	for _, value := range values {
+		tokens = append(tokens, strconv.FormatUint(uint64(value), 10))
+	}
+	return tokens
+}
+
+// parseDocument parses a simulation from a JSON or YAML document, and returns
+// the first error found if any
+func parseDocument(reader io.Reader, format Format) (Simulation, error) {
+	doc, err := decodeDocument(reader, format)
+	if err != nil {
+		return Simulation{}, err
+	}
+	simul, errs := doc.simulation()
+	if len(errs) > 0 {
+		return Simulation{}, errs[0]
+	}
+	return simul, nil
+}
+
+// parse parses a simulation in the given format, which must not be AutoFormat
+func parse(reader io.Reader, format Format) (Simulation, error) {
+	if format == TextFormat {
+		return parseFromReader(reader)
+	}
+	return parseDocument(reader, format)
+}
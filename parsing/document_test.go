@@ -0,0 +1,161 @@
+package parsing
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/adrienlucbert/gofeur/board"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDocument(t *testing.T) {
+	text := strings.Join([]string{
+		"5 5 100",
+		"parcel 1 1 GREEN",
+		"forklift 0 2",
+		"truck 4 4 1000 3",
+		"2 3 4 NS",
+		"3 0 1 *",
+		"2 2",
+		"0 4 2x1",
+	}, "\n")
+	jsonDocument := `{
+		"width": 5, "length": 5, "cycles": 100,
+		"parcels": [{"name": "parcel", "x": 1, "y": 1, "color": "GREEN"}],
+		"forklifts": [{"name": "forklift", "x": 0, "y": 2}],
+		"trucks": [{"name": "truck", "x": 4, "y": 4, "max_weight": 1000, "available": 3}],
+		"tiles": [{"x": 2, "y": 3, "cost": 4, "directions": "NS"}, {"x": 3, "y": 0}],
+		"obstacles": [{"x": 2, "y": 2}, {"x": 0, "y": 4, "width": 2}]
+	}`
+	yamlDocument := strings.Join([]string{
+		"width: 5",
+		"length: 5",
+		"cycles: 100",
+		"parcels:",
+		"  - {name: parcel, x: 1, y: 1, color: GREEN}",
+		"forklifts:",
+		"  - name: forklift",
+		"    x: 0",
+		"    y: 2",
+		"trucks:",
+		"  - {name: truck, x: 4, y: 4, max_weight: 1000, available: 3}",
+		"tiles:",
+		"  - {x: 2, y: 3, cost: 4, directions: NS}",
+		"  - {x: 3, y: 0}",
+		"obstacles:",
+		"  - {x: 2, y: 2}",
+		"  - {x: 0, y: 4, width: 2}",
+	}, "\n")
+
+	expected, err := parseFromReader(strings.NewReader(text))
+	assert.Nil(t, err)
+	assert.Equal(t, []Tile{
+		{coordinate: coordinate{X: 2, Y: 3}, Cost: 4, Directions: board.North | board.South},
+		{coordinate: coordinate{X: 3, Y: 0}, Cost: 1, Directions: board.AnyDirection},
+	}, expected.Warehouse.Tiles)
+
+	simul, err := parse(strings.NewReader(jsonDocument), JSONFormat)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, simul)
+
+	simul, err = parse(strings.NewReader(yamlDocument), YAMLFormat)
+	assert.Nil(t, err)
+	assert.Equal(t, expected, simul)
+}
+
+func TestParseDocumentErrors(t *testing.T) {
+	type testCase struct {
+		input  string
+		format Format
+	}
+
+	testCases := map[string]testCase{
+		"syntax error":         {`{"width": 5,`, JSONFormat},
+		"unknown field":        {`{"width": 5, "length": 5, "cycles": 100, "height": 5}`, JSONFormat},
+		"unknown yaml field":   {"width: 5\nlength: 5\ncycles: 100\nheight: 5", YAMLFormat},
+		"negative coordinate":  {`{"width": 5, "length": 5, "cycles": 100, "forklifts": [{"name": "f", "x": -1, "y": 0}]}`, JSONFormat},
+		"invalid cycles":       {`{"width": 5, "length": 5, "cycles": 1}`, JSONFormat},
+		"invalid color":        {`{"width": 5, "length": 5, "cycles": 100, "parcels": [{"name": "p", "x": 0, "y": 0, "color": "red"}]}`, JSONFormat},
+		"empty name":           {`{"width": 5, "length": 5, "cycles": 100, "forklifts": [{"name": "", "x": 0, "y": 0}]}`, JSONFormat},
+		"zero cost":            {"width: 5\nlength: 5\ncycles: 100\ntiles: [{x: 0, y: 0, cost: 0}]", YAMLFormat},
+		"invalid directions":   {"width: 5\nlength: 5\ncycles: 100\ntiles: [{x: 0, y: 0, directions: up}]", YAMLFormat},
+		"zero obstacle length": {"width: 5\nlength: 5\ncycles: 100\nobstacles: [{x: 0, y: 0, length: 0}]", YAMLFormat},
+		"several documents":    {`{"width": 5, "length": 5, "cycles": 100} {}`, JSONFormat},
+		"several yaml docs":    {"width: 5\nlength: 5\ncycles: 100\n---\nwidth: 5", YAMLFormat},
+	}
+
+	for name, testCase := range testCases {
+		_, err := parse(strings.NewReader(testCase.input), testCase.format)
+		assert.NotNil(t, err, name)
+	}
+}
+
+func TestFormatOf(t *testing.T) {
+	assert.Equal(t, JSONFormat, FormatOf("maps/big.JSON"))
+	assert.Equal(t, YAMLFormat, FormatOf("big.yaml"))
+	assert.Equal(t, YAMLFormat, FormatOf("big.yml"))
+	assert.Equal(t, TextFormat, FormatOf("big.txt"))
+	assert.Equal(t, TextFormat, FormatOf("big"))
+
+	format, err := ParseFormat("YAML")
+	assert.Nil(t, err)
+	assert.Equal(t, YAMLFormat, format)
+	_, err = ParseFormat("toml")
+	assert.NotNil(t, err)
+}
+
+// TestSchemaMatchesDocument ensures that the JSON Schema describes the same
+// fields as the document types
+func TestSchemaMatchesDocument(t *testing.T) {
+	type schema struct {
+		Properties map[string]schema `json:"properties"`
+		Items      *schema           `json:"items"`
+	}
+	var root schema
+	assert.Nil(t, json.Unmarshal(Schema, &root))
+
+	var check func(path string, s schema, typ reflect.Type)
+	check = func(path string, s schema, typ reflect.Type) {
+		fields := []string{}
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+			yamlName, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			assert.Equal(t, name, yamlName, path)
+			fields = append(fields, name)
+
+			if field.Type.Kind() == reflect.Slice {
+				assert.NotNil(t, s.Properties[name].Items, path+"."+name)
+				if s.Properties[name].Items != nil {
+					check(path+"."+name, *s.Properties[name].Items, field.Type.Elem())
+				}
+			}
+		}
+		properties := []string{}
+		for property := range s.Properties {
+			properties = append(properties, property)
+		}
+		assert.ElementsMatch(t, fields, properties, path)
+	}
+	check("scenario", root, reflect.TypeOf(document{}))
+}
+
+func TestDetectFormat(t *testing.T) {
+	testCases := map[string]Format{
+		"5 5 100\nparcel 1 1 green":              TextFormat,
+		"# a comment: with a colon\n\n5 5 100":   TextFormat,
+		"  {\"width\": 5}":                       JSONFormat,
+		"---\nwidth: 5":                          YAMLFormat,
+		"# a yaml scenario\nwidth: 5\nlength: 5": YAMLFormat,
+		"":                                       TextFormat,
+		"5 5:3 100\nparcel 1 1 green":            TextFormat,
+		"parcel:a 1 1 green":                     TextFormat,
+		"width:\n  5":                            YAMLFormat,
+	}
+
+	for input, expected := range testCases {
+		assert.Equal(t, expected, DetectFormat([]byte(input)), input)
+	}
+}
@@ -0,0 +1,230 @@
+package parsing
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"unicode"
+
+	"github.com/adrienlucbert/gofeur/board"
+	"gopkg.in/yaml.v3"
+)
+
+type unencodableNameError struct {
+	kind string
+	name string
+}
+
+func (err unencodableNameError) Error() string {
+	return fmt.Sprintf("the %s named '%s' can't be written in the text format, names must be non-empty, without spaces, and not start with #", err.kind, err.name)
+}
+
+type unencodableParcelError struct {
+	parcel Parcel
+}
+
+func (err unencodableParcelError) Error() string {
+	return fmt.Sprintf("the parcel named %s has neither a known class nor the weight (%d) of one", err.parcel.Name, err.parcel.Weight)
+}
+
+// Encode writes a simulation in the given format, which must not be
+// AutoFormat. The output is canonical: parsing it gives back the same
+// simulation, and encoding that simulation gives back the same output.
+//
+// In the text format, sections are introduced by headers, in the order of the
+// format description, and empty optional sections are left out. Parcel colors
+// are written as their class is declared, which is lowercase for the default
+// classes, and tiles and obstacles omit their default cost, directions and
+// size in the structured formats.
+func Encode(writer io.Writer, simul Simulation, format Format) error {
+	switch format {
+	case TextFormat:
+		return encodeText(writer, simul)
+	case JSONFormat:
+		doc, err := newDocument(simul)
+		if err != nil {
+			return err
+		}
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(doc)
+	case YAMLFormat:
+		doc, err := newDocument(simul)
+		if err != nil {
+			return err
+		}
+		encoder := yaml.NewEncoder(writer)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(doc); err != nil {
+			return err
+		}
+		return encoder.Close()
+	default:
+		panic("Unreachable: Encode needs a concrete format")
+	}
+}
+
+func encodeText(writer io.Writer, simul Simulation) error {
+	warehouse := simul.Warehouse
+	if err := checkNames(warehouse); err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(writer)
+	fmt.Fprintf(w, "%d %d %d\n", warehouse.Width, warehouse.Length, simul.Cycle)
+
+	if len(warehouse.Classes) > 0 {
+		fmt.Fprint(w, "\n[classes]\n")
+	}
+	for _, class := range warehouse.Classes {
+		fmt.Fprintf(w, "%s %d %c %s\n", class.Name, class.Weight, class.Glyph, class.Color)
+	}
+
+	if len(warehouse.Parcels) > 0 {
+		fmt.Fprint(w, "\n[parcels]\n")
+	}
+	for _, parcel := range warehouse.Parcels {
+		color, err := colorOf(parcel, warehouse)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(w, "%s %d %d %s\n", parcel.Name, parcel.X, parcel.Y, color)
+	}
+
+	if len(warehouse.Forklifts) > 0 {
+		fmt.Fprint(w, "\n[forklifts]\n")
+	}
+	for _, forklift := range warehouse.Forklifts {
+		fmt.Fprintf(w, "%s %d %d\n", forklift.Name, forklift.X, forklift.Y)
+	}
+
+	if len(warehouse.Trucks) > 0 {
+		fmt.Fprint(w, "\n[trucks]\n")
+	}
+	for _, truck := range warehouse.Trucks {
+		fmt.Fprintf(w, "%s %d %d %d %d\n", truck.Name, truck.X, truck.Y, truck.MaxWeight, truck.Available)
+	}
+
+	if len(warehouse.Tiles) > 0 {
+		fmt.Fprint(w, "\n[tiles]\n")
+	}
+	for _, tile := range warehouse.Tiles {
+		fmt.Fprintf(w, "%d %d %d %s\n", tile.X, tile.Y, tileCost(tile), tile.Directions)
+	}
+
+	if len(warehouse.Obstacles) > 0 {
+		fmt.Fprint(w, "\n[obstacles]\n")
+	}
+	for _, obstacle := range warehouse.Obstacles {
+		if obstacle.Width == 1 && obstacle.Length == 1 {
+			fmt.Fprintf(w, "%d %d\n", obstacle.X, obstacle.Y)
+		} else {
+			fmt.Fprintf(w, "%d %d %dx%d\n", obstacle.X, obstacle.Y, obstacle.Width, obstacle.Length)
+		}
+	}
+
+	return w.Flush()
+}
+
+// checkNames ensures every name, and every class color, can be read back as a
+// single token
+func checkNames(warehouse Warehouse) error {
+	for _, entity := range makeEntitiesArray(Simulation{Warehouse: warehouse}) {
+		if name := string(entity.stringerName()); !isToken(name) {
+			return unencodableNameError{kind: entity.kind(), name: name}
+		}
+	}
+	for _, class := range warehouse.Classes {
+		if !isToken(class.Name) {
+			return unencodableNameError{kind: "parcel class", name: class.Name}
+		}
+		if !isToken(class.Color) {
+			return unencodableNameError{kind: "color of the parcel class", name: class.Color}
+		}
+	}
+	return nil
+}
+
+func isToken(str string) bool {
+	return str != "" && !strings.HasPrefix(str, "#") && strings.IndexFunc(str, unicode.IsSpace) < 0
+}
+
+// colorOf returns the name of a parcel's class, as declared in the warehouse
+// catalogue
+func colorOf(parcel Parcel, warehouse Warehouse) (string, error) {
+	class, ok := warehouse.ParcelClassOf(parcel)
+	if !ok {
+		return "", unencodableParcelError{parcel: parcel}
+	}
+	return class.Name, nil
+}
+
+func tileCost(tile Tile) uint32 {
+	if tile.Cost == 0 {
+		return 1
+	}
+	return tile.Cost
+}
+
+// newDocument turns a simulation into a JSON or YAML document
+func newDocument(simul Simulation) (document, error) {
+	warehouse := simul.Warehouse
+	doc := document{
+		Width:     uint32(warehouse.Width),
+		Length:    uint32(warehouse.Length),
+		Cycles:    uint32(simul.Cycle),
+		Parcels:   make([]parcelDocument, 0, len(warehouse.Parcels)),
+		Forklifts: make([]forkliftDocument, 0, len(warehouse.Forklifts)),
+		Trucks:    make([]truckDocument, 0, len(warehouse.Trucks)),
+	}
+
+	for _, class := range warehouse.Classes {
+		doc.Classes = append(doc.Classes, classDocument{
+			Name:   class.Name,
+			Weight: uint32(class.Weight),
+			Glyph:  string(class.Glyph),
+			Color:  class.Color,
+		})
+	}
+	for _, parcel := range warehouse.Parcels {
+		color, err := colorOf(parcel, warehouse)
+		if err != nil {
+			return doc, err
+		}
+		doc.Parcels = append(doc.Parcels, parcelDocument{Name: parcel.Name, X: uint32(parcel.X), Y: uint32(parcel.Y), Color: color})
+	}
+	for _, forklift := range warehouse.Forklifts {
+		doc.Forklifts = append(doc.Forklifts, forkliftDocument{Name: forklift.Name, X: uint32(forklift.X), Y: uint32(forklift.Y)})
+	}
+	for _, truck := range warehouse.Trucks {
+		doc.Trucks = append(doc.Trucks, truckDocument{
+			Name:      truck.Name,
+			X:         uint32(truck.X),
+			Y:         uint32(truck.Y),
+			MaxWeight: uint32(truck.MaxWeight),
+			Available: truck.Available,
+		})
+	}
+	for _, tile := range warehouse.Tiles {
+		t := tileDocument{X: uint32(tile.X), Y: uint32(tile.Y)}
+		if cost := tileCost(tile); cost != 1 {
+			t.Cost = &cost
+		}
+		if tile.Directions != board.AnyDirection {
+			t.Directions = tile.Directions.String()
+		}
+		doc.Tiles = append(doc.Tiles, t)
+	}
+	for _, obstacle := range warehouse.Obstacles {
+		o := obstacleDocument{X: uint32(obstacle.X), Y: uint32(obstacle.Y)}
+		if obstacle.Width != 1 || obstacle.Length != 1 {
+			width, length := uint32(obstacle.Width), uint32(obstacle.Length)
+			o.Width, o.Length = &width, &length
+		}
+		doc.Obstacles = append(doc.Obstacles, o)
+	}
+
+	return doc, nil
+}
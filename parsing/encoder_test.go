@@ -0,0 +1,104 @@
+package parsing
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixtures returns every simulation parsed successfully by the parser tests
+func fixtures(t *testing.T) []Simulation {
+	simulations := []Simulation{}
+	for _, testCase := range parseReaderTestCases() {
+		if !testCase.hasError {
+			simulations = append(simulations, testCase.expectedOutput)
+		}
+	}
+	for name, input := range commentsAndHeadersInputs {
+		simul, err := parseFromReader(strings.NewReader(strings.Join(input, "\n")))
+		assert.Nil(t, err, name)
+		simulations = append(simulations, simul)
+	}
+	return simulations
+}
+
+func TestEncodeRoundTrip(t *testing.T) {
+	for _, format := range []Format{TextFormat, JSONFormat, YAMLFormat} {
+		for i, simul := range fixtures(t) {
+			var encoded bytes.Buffer
+			assert.Nil(t, Encode(&encoded, simul, format), "%s fixture %d", format, i)
+
+			parsed, err := parse(bytes.NewReader(encoded.Bytes()), format)
+			assert.Nil(t, err, "%s fixture %d", format, i)
+			assert.Equal(t, simul, parsed, "%s fixture %d", format, i)
+
+			var reencoded bytes.Buffer
+			assert.Nil(t, Encode(&reencoded, parsed, format), "%s fixture %d", format, i)
+			assert.Equal(t, encoded.String(), reencoded.String(), "%s fixture %d", format, i)
+		}
+	}
+}
+
+func TestEncodeText(t *testing.T) {
+	input := strings.Join([]string{
+		"# a small warehouse",
+		"5   5 100",
+		"parcel 1 1 GREEN",
+		"[obstacles]",
+		"2 2",
+		"0 4 2X1",
+		"[tiles]",
+		"2 3 4 sn",
+		"[forklifts]",
+		"forklift\t0 2",
+		"[trucks]",
+		"truck 4 4 1000 3 # the only truck",
+	}, "\n")
+	expected := strings.Join([]string{
+		"5 5 100",
+		"",
+		"[parcels]",
+		"parcel 1 1 green",
+		"",
+		"[forklifts]",
+		"forklift 0 2",
+		"",
+		"[trucks]",
+		"truck 4 4 1000 3",
+		"",
+		"[tiles]",
+		"2 3 4 NS",
+		"",
+		"[obstacles]",
+		"2 2",
+		"0 4 2x1",
+		"",
+	}, "\n")
+
+	simul, err := parseFromReader(strings.NewReader(input))
+	assert.Nil(t, err)
+	var encoded strings.Builder
+	assert.Nil(t, Encode(&encoded, simul, TextFormat))
+	assert.Equal(t, expected, encoded.String())
+}
+
+func TestEncodeErrors(t *testing.T) {
+	simulations := map[string]Simulation{
+		"name with a space": {Warehouse: Warehouse{
+			Forklifts: []Forklift{{Name: "fork lift"}},
+		}},
+		"comment name": {Warehouse: Warehouse{
+			Trucks: []Truck{{Name: "#truck"}},
+		}},
+		"colorless parcel": {Warehouse: Warehouse{
+			Parcels: []Parcel{{Name: "parcel", Weight: 42}},
+		}},
+	}
+
+	for name, simul := range simulations {
+		assert.NotNil(t, Encode(&strings.Builder{}, simul, TextFormat), name)
+	}
+	assert.Nil(t, Encode(&strings.Builder{}, simulations["name with a space"], JSONFormat))
+}
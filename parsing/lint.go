@@ -0,0 +1,228 @@
+package parsing
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/adrienlucbert/gofeur/board"
+	"github.com/adrienlucbert/gofeur/pathfinding"
+	"github.com/adrienlucbert/gofeur/pkg"
+)
+
+type tooHeavyParcelError struct {
+	parcel Parcel
+}
+
+func (err tooHeavyParcelError) Error() string {
+	return fmt.Sprintf("The parcel named %s weighs %d, more than any truck can carry", err.parcel.Name, err.parcel.Weight)
+}
+
+type unreachableParcelError struct {
+	parcel Parcel
+}
+
+func (err unreachableParcelError) Error() string {
+	return fmt.Sprintf("The parcel named %s can't be reached by any forklift", err.parcel.Name)
+}
+
+type undeliverableParcelError struct {
+	parcel Parcel
+}
+
+func (err undeliverableParcelError) Error() string {
+	return fmt.Sprintf("The parcel named %s can't be brought to any truck able to carry it", err.parcel.Name)
+}
+
+type unreachableTruckError struct {
+	truck Truck
+}
+
+func (err unreachableTruckError) Error() string {
+	return fmt.Sprintf("The truck named %s can't be reached by any forklift", err.truck.Name)
+}
+
+type idleForkliftError struct {
+	forklift Forklift
+}
+
+func (err idleForkliftError) Error() string {
+	return fmt.Sprintf("The forklift named %s can't reach any parcel", err.forklift.Name)
+}
+
+// Lint looks for the problems that make a valid simulation impossible, or
+// partly useless, to run, by flood-filling its warehouse:
+//
+//   - a parcel is heavier than what every truck can carry
+//   - a parcel can't be reached by any forklift
+//   - a parcel can't be brought to a truck able to carry it
+//   - a truck can't be reached by any forklift, which is a warning
+//   - a forklift can't reach any parcel, which is a warning
+//
+// Obstacles and trucks never move, so they are the only walls, and tile
+// directions are followed. The simulation must be valid, as told by
+// VerifySimulationValidity. The diagnostics have no file nor line.
+func Lint(simulation Simulation) []Diagnostic {
+	diagnostics := []Diagnostic{}
+	for _, v := range lint(simulation) {
+		diagnostics = append(diagnostics, v.diagnostic("", nil))
+	}
+	return diagnostics
+}
+
+// LintInputFile parses, validates and lints a simulation input file in the
+// given format. Lint checks are only run if the file is valid, so the
+// diagnostics are either the ones of DiagnoseInputFile, or the ones of Lint.
+func LintInputFile(file string, format Format) (Simulation, []Diagnostic, error) {
+	handle, err := os.Open(file)
+	if err != nil {
+		return Simulation{}, nil, inputFileOpenError{file: file, err: err}
+	}
+	defer handle.Close()
+
+	if format == AutoFormat {
+		format = FormatOf(file)
+	}
+	return LintReader(handle, file, format)
+}
+
+// LintReader is LintInputFile for a reader, name being the file name used in
+// diagnostics, as with DiagnoseReader
+func LintReader(reader io.Reader, name string, format Format) (Simulation, []Diagnostic, error) {
+	simul, sources, diagnostics, err := diagnose(reader, name, format)
+	if err != nil || len(diagnostics) > 0 {
+		return simul, diagnostics, err
+	}
+	for _, v := range lint(simul) {
+		diagnostics = append(diagnostics, v.diagnostic(name, sources))
+	}
+	return simul, diagnostics, nil
+}
+
+// lint runs every lint check, and returns all the violations found
+func lint(simulation Simulation) []violation {
+	warehouse := simulation.Warehouse
+	maze := lintBoard(warehouse)
+	violations := []violation{}
+
+	// reach holds, for each forklift, the tiles it can get to
+	reach := make([]pathfinding.DistanceMap, 0, len(warehouse.Forklifts))
+	for _, forklift := range warehouse.Forklifts {
+		reach = append(reach, pathfinding.Distances(&maze, vectorOf(forklift.coordinate)))
+	}
+	// fromTile caches the tiles that can be got to from a tile next to a parcel
+	fromTile := map[pkg.Vector]pathfinding.DistanceMap{}
+	busy := make([]bool, len(warehouse.Forklifts))
+
+	for _, parcel := range warehouse.Parcels {
+		source := entitySourceKey(parcel)
+		trucks := []Truck{}
+		for _, truck := range warehouse.Trucks {
+			if truck.MaxWeight >= parcel.Weight {
+				trucks = append(trucks, truck)
+			}
+		}
+		if len(trucks) == 0 {
+			violations = append(violations, violation{code: codeTooHeavyParcel, err: tooHeavyParcelError{parcel: parcel}, source: source})
+		}
+
+		// the tiles next to the parcel a forklift can grab it from
+		grabbable := []pkg.Vector{}
+		for _, tile := range neighbours(vectorOf(parcel.coordinate)) {
+			reached := false
+			for i := range reach {
+				if _, ok := reach[i].At(tile); ok {
+					busy[i], reached = true, true
+				}
+			}
+			if reached {
+				grabbable = append(grabbable, tile)
+			}
+		}
+		if len(grabbable) == 0 {
+			violations = append(violations, violation{code: codeUnreachableParcel, err: unreachableParcelError{parcel: parcel}, source: source})
+			continue
+		}
+		if len(trucks) == 0 {
+			continue
+		}
+
+		deliverable := false
+		for _, tile := range grabbable {
+			distances, ok := fromTile[tile]
+			if !ok {
+				distances = pathfinding.Distances(&maze, tile)
+				fromTile[tile] = distances
+			}
+			for _, truck := range trucks {
+				deliverable = deliverable || isNextTo(distances, truck.coordinate)
+			}
+		}
+		if !deliverable {
+			violations = append(violations, violation{code: codeUndeliverableParcel, err: undeliverableParcelError{parcel: parcel}, source: source})
+		}
+	}
+
+	for _, truck := range warehouse.Trucks {
+		reachable := false
+		for i := range reach {
+			reachable = reachable || isNextTo(reach[i], truck.coordinate)
+		}
+		if !reachable {
+			violations = append(violations, violation{severity: Warning, code: codeUnreachableTruck, err: unreachableTruckError{truck: truck}, source: entitySourceKey(truck)})
+		}
+	}
+
+	for i, forklift := range warehouse.Forklifts {
+		if !busy[i] && len(warehouse.Parcels) > 0 {
+			violations = append(violations, violation{severity: Warning, code: codeIdleForklift, err: idleForkliftError{forklift: forklift}, source: entitySourceKey(forklift)})
+		}
+	}
+
+	return violations
+}
+
+// lintBoard builds the board of the warehouse, where obstacles and trucks are
+// blocked
+func lintBoard(warehouse Warehouse) board.Board {
+	maze := board.New(uint(warehouse.Width), uint(warehouse.Length))
+	for _, tile := range warehouse.Tiles {
+		maze.At(uint(tile.X), uint(tile.Y)).Cost = uint(tile.Cost)
+		maze.At(uint(tile.X), uint(tile.Y)).Directions = tile.Directions
+	}
+	for _, obstacle := range warehouse.Obstacles {
+		for _, cell := range obstacle.Cells() {
+			maze.At(uint(cell.X), uint(cell.Y)).Obstacle = true
+		}
+	}
+	maze.Clear()
+	for _, truck := range warehouse.Trucks {
+		maze.At(uint(truck.X), uint(truck.Y)).Blocked = true
+	}
+	return maze
+}
+
+// isNextTo returns whether a tile next to coord can be got to. Forklifts
+// interact with parcels and trucks from a neighbour tile, whatever its
+// directions.
+func isNextTo(distances pathfinding.DistanceMap, coord coordinate) bool {
+	for _, tile := range neighbours(vectorOf(coord)) {
+		if _, ok := distances.At(tile); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func neighbours(pos pkg.Vector) []pkg.Vector {
+	return []pkg.Vector{
+		pos.Add(pkg.Vector{X: 0, Y: -1}),
+		pos.Add(pkg.Vector{X: 1, Y: 0}),
+		pos.Add(pkg.Vector{X: 0, Y: 1}),
+		pos.Add(pkg.Vector{X: -1, Y: 0}),
+	}
+}
+
+func vectorOf(coord coordinate) pkg.Vector {
+	return pkg.Vector{X: int(coord.X), Y: int(coord.Y)}
+}
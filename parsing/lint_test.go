@@ -0,0 +1,153 @@
+package parsing
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLintReader(t *testing.T) {
+	type lint struct {
+		line     uint32
+		severity Severity
+		code     string
+	}
+	type testCase struct {
+		name  string
+		input []string
+		lints []lint
+	}
+
+	testCases := []testCase{
+		{
+			name: "feasible",
+			input: []string{
+				"5 5 100",
+				"parcel 2 2 blue",
+				"forklift 0 2",
+				"truck 4 4 500 3",
+			},
+			lints: []lint{},
+		},
+		{
+			name: "too heavy parcel",
+			input: []string{
+				"5 5 100",
+				"parcel_a 2 2 blue",
+				"parcel_b 1 1 yellow",
+				"forklift 0 2",
+				"truck 4 4 200 3",
+			},
+			lints: []lint{{2, Error, codeTooHeavyParcel}},
+		},
+		{
+			name: "enclosed parcel",
+			input: []string{
+				"5 5 100",
+				"[parcels]",
+				"parcel 1 1 yellow",
+				"[forklifts]",
+				"forklift 4 2",
+				"[trucks]",
+				"truck 4 4 500 3",
+				"[obstacles]",
+				"0 0 3x1",
+				"0 1",
+				"2 1",
+				"0 2 3x1",
+			},
+			lints: []lint{{3, Error, codeUnreachableParcel}, {5, Warning, codeIdleForklift}},
+		},
+		{
+			name: "enclosed truck",
+			input: []string{
+				"5 5 100",
+				"[parcels]",
+				"parcel 2 2 yellow",
+				"[forklifts]",
+				"forklift 0 2",
+				"[trucks]",
+				"truck_a 4 4 500 3",
+				"truck_b 0 0 100 3",
+				"[obstacles]",
+				"3 4",
+				"4 3",
+			},
+			lints: []lint{{7, Warning, codeUnreachableTruck}},
+		},
+		{
+			name: "undeliverable parcel",
+			input: []string{
+				"5 5 100",
+				"[parcels]",
+				"parcel 2 2 blue",
+				"[forklifts]",
+				"forklift 0 2",
+				"[trucks]",
+				"truck_a 4 4 500 3",
+				"truck_b 0 0 100 3",
+				"[obstacles]",
+				"3 4",
+				"4 3",
+			},
+			lints: []lint{{3, Error, codeUndeliverableParcel}, {7, Warning, codeUnreachableTruck}},
+		},
+		{
+			name: "one-way tiles",
+			input: []string{
+				"5 5 100",
+				"[parcels]",
+				"parcel 4 2 yellow",
+				"[forklifts]",
+				"forklift 0 2",
+				"[trucks]",
+				"truck 0 0 500 3",
+				"[tiles]",
+				"2 2 1 E",
+				"[obstacles]",
+				"2 0 1x2",
+				"2 3 1x2",
+			},
+			lints: []lint{{3, Error, codeUndeliverableParcel}},
+		},
+		{
+			name: "invalid input",
+			input: []string{
+				"5 5 100",
+				"parcel 2 2 blue",
+				"forklift 0 2",
+				"truck 2 2 100 3",
+			},
+			lints: []lint{{4, Error, codeTruckNotOnSide}, {4, Error, codeStackedEntities}},
+		},
+	}
+
+	for _, tc := range testCases {
+		_, diagnostics, err := LintReader(strings.NewReader(strings.Join(tc.input, "\n")), "map.txt", TextFormat)
+		assert.Nil(t, err, tc.name)
+		lints := []lint{}
+		for _, diagnostic := range diagnostics {
+			lints = append(lints, lint{diagnostic.Line, diagnostic.Severity, diagnostic.Code})
+		}
+		assert.Equal(t, tc.lints, lints, tc.name)
+	}
+}
+
+func TestLint(t *testing.T) {
+	simul, err := ParseReader(strings.NewReader("5 5 100\nparcel 2 2 blue\nforklift 0 2\ntruck 4 4 100 3"), TextFormat)
+	assert.Nil(t, err)
+
+	diagnostics := Lint(simul)
+	assert.Len(t, diagnostics, 1)
+	assert.Equal(t, Diagnostic{
+		Severity: Error,
+		Code:     codeTooHeavyParcel,
+		Message:  "The parcel named parcel weighs 500, more than any truck can carry",
+	}, diagnostics[0])
+}
+
+func TestWarningString(t *testing.T) {
+	diagnostic := Diagnostic{File: "map.txt", Line: 4, Column: 1, Severity: Warning, Code: codeIdleForklift, Message: "The forklift named forklift can't reach any parcel"}
+	assert.Equal(t, "map.txt:4:1: warning: The forklift named forklift can't reach any parcel [L005]", diagnostic.String())
+}
@@ -3,13 +3,16 @@ package parsing
 
 import (
 	"bufio"
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"os"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
+	"github.com/adrienlucbert/gofeur/board"
 	"github.com/adrienlucbert/gofeur/optional"
 )
 
@@ -29,6 +32,10 @@ const (
 	unitTokenKind
 	weightTokenKind
 	parcelColorTokenKind
+	costTokenKind
+	directionTokenKind
+	sizeTokenKind
+	glyphTokenKind
 )
 
 const (
@@ -37,10 +44,15 @@ const (
 	invalidUnsignedInteger
 	invalidCycleNumber
 	invalidWeight
+	invalidCost
+	invalidDirection
+	invalidSize
+	invalidGlyph
 )
 
 type inputError struct {
 	line    uint32
+	column  uint32
 	section string
 	err     error
 }
@@ -66,6 +78,14 @@ func (kind parserErrorKind) ToString() string {
 		return "invalid weight"
 	case invalidCycleNumber:
 		return "invalid cycle number"
+	case invalidCost:
+		return "invalid cost"
+	case invalidDirection:
+		return "invalid direction"
+	case invalidSize:
+		return "invalid size"
+	case invalidGlyph:
+		return "invalid glyph"
 	default:
 		panic("Unreachable")
 	}
@@ -81,6 +101,8 @@ type fieldTokenError struct {
 	token     string
 	kind      parserErrorKind
 	err       string
+	// index is the index of the invalid token in its line
+	index int
 }
 
 func (err fieldTokenError) Error() string {
@@ -122,88 +144,220 @@ func (err inputFileError) Error() string {
 	return fmt.Sprintf("Error in input file '%s': %s", err.file, err.err.Error())
 }
 
-// ParseInputFile parses a simulation input file. If successful, return the
-// parsed Simulation.
+// ParseInputFile parses a simulation input file, which format is picked from
+// its extension. If successful, return the parsed Simulation.
 func ParseInputFile(file string) (Simulation, error) {
+	return ParseInputFileAs(file, AutoFormat)
+}
+
+// ParseInputFileAs parses a simulation input file in the given format. If
+// successful, return the parsed Simulation.
+func ParseInputFileAs(file string, format Format) (Simulation, error) {
 	handle, err := os.Open(file)
 	if err != nil {
-		return Simulation{}, inputFileOpenError{err: err}
+		return Simulation{}, inputFileOpenError{file: file, err: err}
 	}
 	defer handle.Close()
 
-	simul, err := parseFromReader(handle)
+	if format == AutoFormat {
+		format = FormatOf(file)
+	}
+	simul, err := parse(handle, format)
 	if err != nil {
 		err = inputFileError{file: file, err: err}
 	}
 	return simul, err
 }
 
-var errInvalidLine = errors.New("invalid line")
+// ParseReader parses a simulation from a reader, such as the standard input or
+// an HTTP request body. With AutoFormat, the format is guessed from the
+// content, as told by DetectFormat.
+func ParseReader(reader io.Reader, format Format) (Simulation, error) {
+	if format == AutoFormat {
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			return Simulation{}, err
+		}
+		reader = bytes.NewReader(data)
+		format = DetectFormat(data)
+	}
+	return parse(reader, format)
+}
+
+var (
+	errInvalidLine           = errors.New("invalid line")
+	errHeaderBeforeWarehouse = errors.New("the warehouse line must come before any section header")
+	errClassesAfterParcels   = errors.New("parcel classes must be declared before any parcel")
+)
+
+type unknownSectionError struct {
+	header string
+}
+
+func (err unknownSectionError) Error() string {
+	return fmt.Sprintf("unknown section header '%s'", err.header)
+}
 
+// sectionHeaders maps explicit section headers to the sections they start
+var sectionHeaders = map[string]string{
+	"[classes]":   "class",
+	"[parcels]":   "parcel",
+	"[forklifts]": "forklift",
+	"[trucks]":    "truck",
+	"[tiles]":     "tile",
+	"[obstacles]": "obstacle",
+}
+
+// tokenize splits a line into tokens, dropping everything from the first token
+// starting with a # on, as it's a comment
+func tokenize(line string) []string {
+	tokens := strings.Fields(line)
+	for i, token := range tokens {
+		if strings.HasPrefix(token, "#") {
+			return tokens[:i]
+		}
+	}
+	return tokens
+}
+
+func isSectionHeader(tokens []string) bool {
+	return len(tokens) == 1 && strings.HasPrefix(tokens[0], "[") && strings.HasSuffix(tokens[0], "]")
+}
+
+// parseFromReader parses a simulation from an input file, and returns the
+// first error found if any
 func parseFromReader(reader io.Reader) (Simulation, error) {
+	p := newParser(false)
+	p.parse(reader)
+	if len(p.errors) > 0 {
+		return Simulation{}, p.errors[0]
+	}
+	return p.simul, nil
+}
+
+// parser parses a simulation from an input file. Blank lines and # comments
+// are ignored. After the warehouse line, sections may be introduced by
+// explicit headers, in any order. Without headers, sections are expected in
+// order, and are told apart by their number of tokens.
+//
+// The parser stops at the first error, unless it collects them all, in which
+// case invalid lines are skipped. It also records where each entity was
+// declared.
+type parser struct {
+	collect  bool
+	section  string
+	line     uint32
+	explicit bool
+	simul    Simulation
+	sources  sourceMap
+	errors   []inputError
+}
+
+func newParser(collect bool) *parser {
+	return &parser{collect: collect, section: "warehouse", sources: sourceMap{}}
+}
+
+// fail records an error on the current line, and returns whether parsing
+// should go on
+func (p *parser) fail(err error) bool {
+	column := uint32(1)
+	if tokenErr, ok := err.(fieldTokenError); ok && tokenErr.kind != invalidNumberOfTokens {
+		column = uint32(tokenErr.index) + 1
+	}
+	p.errors = append(p.errors, inputError{line: p.line, column: column, section: p.section, err: err})
+	return p.collect
+}
+
+func (p *parser) parse(reader io.Reader) {
 	scanner := bufio.NewScanner(reader)
-	parser := struct {
-		section                  string
-		line                     uint32
-		tokens                   []string
-		mayBeParsedByNextSection bool
-	}{section: "warehouse"}
-	simul := Simulation{}
-	var err parserError
 
-	for parser.mayBeParsedByNextSection || scanner.Scan() {
-		if !parser.mayBeParsedByNextSection {
-			parser.line++
-			line := scanner.Text()
-			parser.tokens = strings.Split(line, " ")
+	for scanner.Scan() {
+		p.line++
+		if !p.parseLine(tokenize(scanner.Text())) {
+			return
 		}
-		parser.mayBeParsedByNextSection = false
-
-		switch parser.section {
-		case "warehouse":
-			simul, err = parseWarehouseSection(parser.tokens)
-
-			if err != nil {
-				return Simulation{}, inputError{
-					line:    parser.line,
-					section: parser.section,
-					err:     err,
-				}
-			}
-			parser.section = getNextSection(parser.section).Value()
+	}
+
+	if err := scanner.Err(); err != nil {
+		p.fail(err)
+	}
+}
+
+// parseLine parses a line, and returns whether parsing should go on
+func (p *parser) parseLine(tokens []string) bool {
+	if len(tokens) == 0 {
+		return true
+	}
 
-		default:
-			err = parseWarehouseEntity(parser.section, parser.tokens, &simul.Warehouse)
+	if isSectionHeader(tokens) {
+		section, ok := sectionHeaders[strings.ToLower(tokens[0])]
+		switch {
+		case p.section == "warehouse":
+			return p.fail(errHeaderBeforeWarehouse)
+		case !ok:
+			return p.fail(unknownSectionError{header: tokens[0]})
 		}
+		p.section = section
+		p.explicit = true
+		return true
+	}
 
+	if p.section == "warehouse" {
+		simul, err := parseWarehouseSection(tokens)
+		p.sources[sourceKey{kind: "warehouse"}] = p.line
+		p.section = getNextSection(p.section).Value()
 		if err != nil {
-			if err.Kind() == invalidNumberOfTokens {
-				nextSection := getNextSection(parser.section)
-
-				if nextSection.HasValue() {
-					parser.section = nextSection.Value()
-					parser.mayBeParsedByNextSection = true
-				} else {
-					return Simulation{}, inputError{
-						line:    parser.line,
-						section: parser.section,
-						err:     errInvalidLine,
-					}
-				}
-			} else {
-				break
-			}
+			return p.fail(err)
 		}
+		p.simul = simul
+		return true
 	}
 
-	if err != nil {
-		return Simulation{}, inputError{
-			line:    parser.line,
-			section: parser.section,
-			err:     err,
+	if p.section == "class" && len(p.simul.Warehouse.Parcels) > 0 {
+		return p.fail(errClassesAfterParcels)
+	}
+
+	section := p.section
+	for {
+		err := parseWarehouseEntity(section, tokens, &p.simul.Warehouse)
+		if err == nil {
+			p.section = section
+			p.recordSource(section)
+			return true
 		}
+		if err.Kind() != invalidNumberOfTokens || p.explicit {
+			return p.fail(err)
+		}
+		nextSection := getNextSection(section)
+		if !nextSection.HasValue() {
+			return p.fail(errInvalidLine)
+		}
+		section = nextSection.Value()
+	}
+}
+
+// recordSource records the current line as where the last entity of the given
+// section was declared
+func (p *parser) recordSource(section string) {
+	warehouse := &p.simul.Warehouse
+	var key sourceKey
+	switch section {
+	case "parcel":
+		key = entitySourceKey(warehouse.Parcels[len(warehouse.Parcels)-1])
+	case "forklift":
+		key = entitySourceKey(warehouse.Forklifts[len(warehouse.Forklifts)-1])
+	case "truck":
+		key = entitySourceKey(warehouse.Trucks[len(warehouse.Trucks)-1])
+	case "tile":
+		key = sourceKey{kind: "tile", coord: warehouse.Tiles[len(warehouse.Tiles)-1].coordinate}
+	case "obstacle":
+		key = sourceKey{kind: "obstacle", coord: warehouse.Obstacles[len(warehouse.Obstacles)-1].coordinate}
+	case "class":
+		key = sourceKey{kind: "class", name: warehouse.Classes[len(warehouse.Classes)-1].Name}
+	}
+	if _, ok := p.sources[key]; !ok {
+		p.sources[key] = p.line
 	}
-	return simul, nil
 }
 
 func getNextSection(section string) optional.Optional[string] {
@@ -214,6 +368,10 @@ func getNextSection(section string) optional.Optional[string] {
 		return optional.New("forklift")
 	case "forklift":
 		return optional.New("truck")
+	case "truck":
+		return optional.New("tile")
+	case "tile":
+		return optional.New("obstacle")
 	default:
 		return optional.NewEmpty[string]()
 	}
@@ -246,9 +404,16 @@ func parseWarehouseEntity(section string, tokens []string, warehouse *Warehouse)
 	var err parserError
 
 	switch section {
+	case "class":
+		var class ParcelClass
+		class, err = parseClass(tokens)
+
+		if err == nil {
+			warehouse.Classes = append(warehouse.Classes, class)
+		}
 	case "parcel":
 		var parcel Parcel
-		parcel, err = parseParcel(tokens)
+		parcel, err = parseParcel(tokens, warehouse.ParcelClasses())
 
 		if err == nil {
 			warehouse.Parcels = append(warehouse.Parcels, parcel)
@@ -267,13 +432,64 @@ func parseWarehouseEntity(section string, tokens []string, warehouse *Warehouse)
 		if err == nil {
 			warehouse.Trucks = append(warehouse.Trucks, truck)
 		}
+	case "tile":
+		var tile Tile
+		tile, err = parseTile(tokens)
+
+		if err == nil {
+			warehouse.Tiles = append(warehouse.Tiles, tile)
+		}
+	case "obstacle":
+		var obstacle Obstacle
+		obstacle, err = parseObstacle(tokens)
+
+		if err == nil {
+			warehouse.Obstacles = append(warehouse.Obstacles, obstacle)
+		}
 	}
 
 	return err
 }
 
-func parseParcel(tokens []string) (Parcel, parserError) {
+// parseClass parses a parcel class (name weight glyph color)
+func parseClass(tokens []string) (ParcelClass, parserError) {
+	class := ParcelClass{}
+	classTokenParsers := []tokenParser{
+		{
+			fieldName: "name",
+			kind:      nonEmptyStringTokenKind,
+			value:     &class.Name,
+		},
+		{
+			fieldName: "weight",
+			kind:      weightTokenKind,
+			value:     &class.Weight,
+		},
+		{
+			fieldName: "glyph",
+			kind:      glyphTokenKind,
+			value:     &class.Glyph,
+		},
+		{
+			fieldName: "color",
+			kind:      nonEmptyStringTokenKind,
+			value:     &class.Color,
+		},
+	}
+
+	err := parseTokens(tokens, classTokenParsers)
+	return class, err
+}
+
+// classToken is what a parcel's class is parsed into, from a catalogue
+type classToken struct {
+	classes []ParcelClass
+	class   ParcelClass
+}
+
+func parseParcel(tokens []string, classes []ParcelClass) (Parcel, parserError) {
 	pkg := Parcel{}
+	class := classToken{classes: classes}
 	parcelTokenParsers := []tokenParser{
 		{
 			fieldName: "name",
@@ -293,14 +509,14 @@ func parseParcel(tokens []string) (Parcel, parserError) {
 		{
 			fieldName: "color",
 			kind:      parcelColorTokenKind,
-			value:     &pkg.Weight,
+			value:     &class,
 		},
 	}
 
 	err := parseTokens(tokens, parcelTokenParsers)
 	if err == nil {
-		colorTokenIndex := len(parcelTokenParsers) - 1
-		pkg.Color = strings.ToLower(tokens[colorTokenIndex])
+		pkg.Color = class.class.Name
+		pkg.Weight = class.class.Weight
 	}
 	return pkg, err
 }
@@ -360,6 +576,62 @@ func parseTruck(tokens []string) (Truck, parserError) {
 	return lorry, err
 }
 
+func parseTile(tokens []string) (Tile, parserError) {
+	tile := Tile{}
+	tileTokenParsers := []tokenParser{
+		{
+			fieldName: "x",
+			kind:      unitTokenKind,
+			value:     &tile.X,
+		},
+		{
+			fieldName: "y",
+			kind:      unitTokenKind,
+			value:     &tile.Y,
+		},
+		{
+			fieldName: "cost",
+			kind:      costTokenKind,
+			value:     &tile.Cost,
+		},
+		{
+			fieldName: "directions",
+			kind:      directionTokenKind,
+			value:     &tile.Directions,
+		},
+	}
+
+	err := parseTokens(tokens, tileTokenParsers)
+	return tile, err
+}
+
+// parseObstacle parses either a single cell (x y) or a rectangle (x y WxL)
+func parseObstacle(tokens []string) (Obstacle, parserError) {
+	obstacle := Obstacle{Width: 1, Length: 1}
+	obstacleTokenParsers := []tokenParser{
+		{
+			fieldName: "x",
+			kind:      unitTokenKind,
+			value:     &obstacle.X,
+		},
+		{
+			fieldName: "y",
+			kind:      unitTokenKind,
+			value:     &obstacle.Y,
+		},
+	}
+	if len(tokens) == len(obstacleTokenParsers)+1 {
+		obstacleTokenParsers = append(obstacleTokenParsers, tokenParser{
+			fieldName: "size",
+			kind:      sizeTokenKind,
+			value:     &obstacle,
+		})
+	}
+
+	err := parseTokens(tokens, obstacleTokenParsers)
+	return obstacle, err
+}
+
 func parseTokens(tokens []string, tokenParsers []tokenParser) parserError {
 	if len(tokens) != len(tokenParsers) {
 		return fieldTokenError{kind: invalidNumberOfTokens}
@@ -377,6 +649,7 @@ func parseTokens(tokens []string, tokenParsers []tokenParser) parserError {
 				err:       tokenErr.Error(),
 				fieldName: tokenParser.fieldName,
 				token:     token,
+				index:     i,
 			}
 		}
 	}
@@ -397,6 +670,14 @@ func parseToken(token string, kind tokenKind, value any) error {
 		err = parseUnitToken(token, kind, ptr)
 	case *weight:
 		err = parseWeightToken(token, kind, ptr)
+	case *board.Direction:
+		err = parseDirectionToken(token, kind, ptr)
+	case *Obstacle:
+		err = parseSizeToken(token, kind, ptr)
+	case *rune:
+		err = parseGlyphToken(token, kind, ptr)
+	case *classToken:
+		err = parseClassToken(token, kind, ptr)
 	default:
 		panic("Unreachable: Unexpected pointer type")
 	}
@@ -418,16 +699,29 @@ func parseStringToken(token string, kind tokenKind, ptr *string) parserError {
 	return nil
 }
 
-func parseUint32Token(token string, _ tokenKind, ptr *uint32) parserError {
+func parseUint32Token(token string, kind tokenKind, ptr *uint32) parserError {
 	value, err := parseUint32Field(token)
 
 	if err == nil {
+		if kind == costTokenKind && value == 0 {
+			return tokenError{kind: invalidCost, err: "should be at least 1"}
+		}
 		*ptr = value
 		return nil
 	}
 	return tokenError{kind: invalidUnsignedInteger, err: err.Error()}
 }
 
+func parseDirectionToken(token string, _ tokenKind, ptr *board.Direction) parserError {
+	direction, err := board.ParseDirection(token)
+
+	if err == nil {
+		*ptr = direction
+		return nil
+	}
+	return tokenError{kind: invalidDirection, err: err.Error()}
+}
+
 func parseSimulationCycleToken(token string, _ tokenKind, ptr *SimulationCycle) parserError {
 	value, err := parseUint32Field(token)
 
@@ -461,14 +755,6 @@ func parseWeightToken(token string, kind tokenKind, ptr *weight) parserError {
 		} else {
 			return tokenError{kind: invalidWeight, err: err.Error()}
 		}
-	case parcelColorTokenKind:
-		parcelWeight, err := parseWeight(token)
-
-		if err == nil {
-			*ptr = parcelWeight
-		} else {
-			return tokenError{kind: invalidWeight, err: err.Error()}
-		}
 	default:
 		panic("Unreachable: Unexpected TokenParserKind")
 	}
@@ -476,6 +762,46 @@ func parseWeightToken(token string, kind tokenKind, ptr *weight) parserError {
 	return nil
 }
 
+func parseClassToken(token string, _ tokenKind, ptr *classToken) parserError {
+	class, err := findParcelClass(ptr.classes, token)
+
+	if err == nil {
+		ptr.class = class
+		return nil
+	}
+	return tokenError{kind: invalidWeight, err: err.Error()}
+}
+
+// parseGlyphToken parses a single character, other than the ones the board
+// uses for empty cells and obstacles
+func parseGlyphToken(token string, _ tokenKind, ptr *rune) parserError {
+	if utf8.RuneCountInString(token) != 1 || token == "." || token == "#" {
+		return tokenError{kind: invalidGlyph, err: "should be a single character, other than . and #"}
+	}
+	*ptr, _ = utf8.DecodeRuneInString(token)
+	return nil
+}
+
+// parseSizeToken parses a WxL token, such as 3x2, into the obstacle's width
+// and length
+func parseSizeToken(token string, _ tokenKind, ptr *Obstacle) parserError {
+	width, length, found := strings.Cut(strings.ToLower(token), "x")
+	if !found {
+		return tokenError{kind: invalidSize, err: "should be formatted as WxL"}
+	}
+	w, err := parseUint32Field(width)
+	if err != nil || w == 0 {
+		return tokenError{kind: invalidSize, err: "width should be a positive integer"}
+	}
+	l, err := parseUint32Field(length)
+	if err != nil || l == 0 {
+		return tokenError{kind: invalidSize, err: "length should be a positive integer"}
+	}
+	ptr.Width = gridUnit(w)
+	ptr.Length = gridUnit(l)
+	return nil
+}
+
 func parseUint32Field(token string) (uint32, error) {
 	value, err := strconv.ParseUint(token, 10, 32)
 	return uint32(value), err
@@ -483,15 +809,14 @@ func parseUint32Field(token string) (uint32, error) {
 
 var errInvalidColor = errors.New("invalid color")
 
-func parseWeight(maybeColor string) (weight, error) {
-	colors := []string{"yellow", "green", "blue"}
-	colorsWeight := []weight{yellow, green, blue}
-
-	for i, color := range colors {
-		if color == maybeColor || strings.ToUpper(color) == maybeColor {
-			return colorsWeight[i], nil
+// findParcelClass returns the class with the given name, in lower or upper
+// case
+func findParcelClass(classes []ParcelClass, maybeColor string) (ParcelClass, error) {
+	for _, class := range classes {
+		if class.Name == maybeColor || strings.ToUpper(class.Name) == maybeColor {
+			return class, nil
 		}
 	}
 
-	return yellow, errInvalidColor
+	return ParcelClass{}, errInvalidColor
 }
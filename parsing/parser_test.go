@@ -4,15 +4,20 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/adrienlucbert/gofeur/board"
 	"github.com/stretchr/testify/assert"
 )
 
-func TestParseReader(t *testing.T) {
-	type testCase struct {
-		input          []string
-		expectedOutput Simulation
-		hasError       bool
-	}
+type parseReaderTestCase struct {
+	input          []string
+	expectedOutput Simulation
+	hasError       bool
+}
+
+// parseReaderTestCases returns the input files fixtures, which are also used
+// by the round-trip tests
+func parseReaderTestCases() []parseReaderTestCase {
+	type testCase = parseReaderTestCase
 
 	testCases := []testCase{
 		{
@@ -112,6 +117,114 @@ func TestParseReader(t *testing.T) {
 		},
 	}
 
+	testCases = append(testCases, testCase{
+		input: []string{
+			"10 50 243",
+			"forklift 1 10",
+			"truck 0 5 10000 60",
+			"2 3 4 *",
+			"3 3 1 en",
+		},
+		expectedOutput: Simulation{
+			Cycle: 243,
+			Warehouse: Warehouse{
+				Width: 10, Length: 50,
+				Forklifts: []Forklift{
+					{Name: "forklift", coordinate: coordinate{X: 1, Y: 10}},
+				},
+				Trucks: []Truck{
+					{Name: "truck", coordinate: coordinate{X: 0, Y: 5}, MaxWeight: 10000, Available: 60},
+				},
+				Tiles: []Tile{
+					{coordinate: coordinate{X: 2, Y: 3}, Cost: 4, Directions: board.AnyDirection},
+					{coordinate: coordinate{X: 3, Y: 3}, Cost: 1, Directions: board.North | board.East},
+				},
+			},
+		},
+	}, testCase{
+		input: []string{
+			"10 50 243",
+			"forklift 1 10",
+			"truck 0 5 10000 60",
+			"2 3 4 *",
+			"forklift_b 1 11",
+		},
+		hasError: true,
+	}, testCase{
+		input: []string{
+			"10 50 243",
+			"forklift 1 10",
+			"truck 0 5 10000 60",
+			"4 4",
+			"5 0 1x10",
+		},
+		expectedOutput: Simulation{
+			Cycle: 243,
+			Warehouse: Warehouse{
+				Width: 10, Length: 50,
+				Forklifts: []Forklift{
+					{Name: "forklift", coordinate: coordinate{X: 1, Y: 10}},
+				},
+				Trucks: []Truck{
+					{Name: "truck", coordinate: coordinate{X: 0, Y: 5}, MaxWeight: 10000, Available: 60},
+				},
+				Obstacles: []Obstacle{
+					{coordinate: coordinate{X: 4, Y: 4}, Width: 1, Length: 1},
+					{coordinate: coordinate{X: 5, Y: 0}, Width: 1, Length: 10},
+				},
+			},
+		},
+	}, testCase{
+		input: []string{
+			"6 6 100",
+			"[classes]",
+			"fragile 50 f pink",
+			"Pallet 1500 ▣ ff8800",
+			"[parcels]",
+			"vase 1 1 fragile",
+			"crate 2 1 PALLET",
+			"[forklifts]",
+			"forklift 0 0",
+			"[trucks]",
+			"truck 5 5 3000 10",
+		},
+		expectedOutput: Simulation{
+			Cycle: 100,
+			Warehouse: Warehouse{
+				Width: 6, Length: 6,
+				Classes: []ParcelClass{
+					{Name: "fragile", Weight: 50, Glyph: 'f', Color: "pink"},
+					{Name: "Pallet", Weight: 1500, Glyph: '▣', Color: "ff8800"},
+				},
+				Parcels: []Parcel{
+					{Name: "vase", coordinate: coordinate{X: 1, Y: 1}, Color: "fragile", Weight: 50},
+					{Name: "crate", coordinate: coordinate{X: 2, Y: 1}, Color: "Pallet", Weight: 1500},
+				},
+				Forklifts: []Forklift{
+					{Name: "forklift", coordinate: coordinate{X: 0, Y: 0}},
+				},
+				Trucks: []Truck{
+					{Name: "truck", coordinate: coordinate{X: 5, Y: 5}, MaxWeight: 3000, Available: 10},
+				},
+			},
+		},
+	}, testCase{
+		input: []string{
+			"6 6 100",
+			"[classes]",
+			"fragile 50 f pink",
+			"[parcels]",
+			"vase 1 1 green",
+		},
+		hasError: true,
+	})
+
+	return testCases
+}
+
+func TestParseReader(t *testing.T) {
+	testCases := parseReaderTestCases()
+
 	for _, testCase := range testCases {
 		input := strings.Join(testCase.input, "\n")
 		reader := strings.NewReader(input)
@@ -125,6 +238,101 @@ func TestParseReader(t *testing.T) {
 	}
 }
 
+// commentsAndHeadersInputs describe the same simulation in different ways
+var commentsAndHeadersInputs = map[string][]string{
+	"legacy": {
+		"5 5 100",
+		"parcel 1 1 green",
+		"forklift 0 2",
+		"truck 4 4 1000 3",
+		"2 2",
+	},
+	"comments and blank lines": {
+		"# a 5x5 warehouse",
+		"5 5 100",
+		"",
+		"parcel 1 1 green # the only parcel",
+		"   ",
+		"forklift   0 2",
+		"\ttruck 4 4 1000 3",
+		"#2 2",
+		"2 2",
+	},
+	"headers in any order": {
+		"5 5 100",
+		"[obstacles]",
+		"2 2",
+		"[trucks]",
+		"truck 4 4 1000 3",
+		"",
+		"[forklifts] # headers are case-insensitive",
+		"forklift 0 2",
+		"[PARCELS]",
+		"parcel 1 1 green",
+	},
+}
+
+func TestParseReaderCommentsAndHeaders(t *testing.T) {
+	expected := Simulation{
+		Cycle: 100,
+		Warehouse: Warehouse{
+			Width: 5, Length: 5,
+			Parcels: []Parcel{
+				{Name: "parcel", coordinate: coordinate{X: 1, Y: 1}, Color: "green", Weight: green},
+			},
+			Forklifts: []Forklift{
+				{Name: "forklift", coordinate: coordinate{X: 0, Y: 2}},
+			},
+			Trucks: []Truck{
+				{Name: "truck", coordinate: coordinate{X: 4, Y: 4}, MaxWeight: 1000, Available: 3},
+			},
+			Obstacles: []Obstacle{
+				{coordinate: coordinate{X: 2, Y: 2}, Width: 1, Length: 1},
+			},
+		},
+	}
+
+	for name, input := range commentsAndHeadersInputs {
+		simul, err := parseFromReader(strings.NewReader(strings.Join(input, "\n")))
+		assert.Nil(t, err, name)
+		assert.Equal(t, expected, simul, name)
+	}
+}
+
+func TestParseReaderHeaderErrors(t *testing.T) {
+	inputs := map[string][]string{
+		"header before warehouse": {
+			"[parcels]",
+			"5 5 100",
+		},
+		"unknown header": {
+			"5 5 100",
+			"[pallets]",
+		},
+		"entity in the wrong section": {
+			"5 5 100",
+			"[trucks]",
+			"forklift 0 2",
+		},
+		"classes after parcels": {
+			"5 5 100",
+			"[parcels]",
+			"parcel 1 1 green",
+			"[classes]",
+			"fragile 50 f pink",
+		},
+		"classes without header": {
+			"5 5 100",
+			"fragile 50 f pink",
+		},
+	}
+
+	for name, input := range inputs {
+		_, err := parseFromReader(strings.NewReader(strings.Join(input, "\n")))
+		assert.NotNil(t, err, name)
+	}
+}
+
 func TestParseWarehouseSection(t *testing.T) {
 	type testCase struct {
 		input          []string
@@ -237,7 +445,7 @@ func TestParseParcel(t *testing.T) {
 	}
 
 	for _, testCase := range testCases {
-		parcel, err := parseParcel(testCase.input)
+		parcel, err := parseParcel(testCase.input, DefaultParcelClasses())
 
 		if testCase.hasError {
 			assert.Equal(t, err.Kind(), testCase.errorKind)
@@ -247,6 +455,52 @@ func TestParseParcel(t *testing.T) {
 	}
 }
 
+func TestParseClass(t *testing.T) {
+	type testCase struct {
+		input          []string
+		expectedOutput ParcelClass
+		hasError       bool
+		errorKind      parserErrorKind
+	}
+
+	testCases := []testCase{
+		{
+			input:     []string{"fragile", "50", "f"},
+			hasError:  true,
+			errorKind: invalidNumberOfTokens,
+		},
+		{
+			input:     []string{"fragile", "heavy", "f", "pink"},
+			hasError:  true,
+			errorKind: invalidWeight,
+		},
+		{
+			input:     []string{"fragile", "50", "ff", "pink"},
+			hasError:  true,
+			errorKind: invalidGlyph,
+		},
+		{
+			input:     []string{"fragile", "50", ".", "pink"},
+			hasError:  true,
+			errorKind: invalidGlyph,
+		},
+		{
+			input:          []string{"fragile", "50", "é", "pink"},
+			expectedOutput: ParcelClass{Name: "fragile", Weight: 50, Glyph: 'é', Color: "pink"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		class, err := parseClass(testCase.input)
+
+		if testCase.hasError {
+			assert.Equal(t, err.Kind(), testCase.errorKind)
+		} else {
+			assert.Equal(t, class, testCase.expectedOutput)
+		}
+	}
+}
+
 func TestParseForklift(t *testing.T) {
 	type testCase struct {
 		input          []string
@@ -313,7 +567,93 @@ func TestParseTruck(t *testing.T) {
 	}
 }
 
-func TestParseWeight(t *testing.T) {
+func TestParseTile(t *testing.T) {
+	type testCase struct {
+		input          []string
+		expectedOutput Tile
+		hasError       bool
+		errorKind      parserErrorKind
+	}
+
+	testCases := []testCase{
+		{
+			input:          []string{"2", "3", "4", "sw"},
+			expectedOutput: Tile{coordinate: coordinate{X: 2, Y: 3}, Cost: 4, Directions: board.South | board.West},
+		},
+		{
+			input:     []string{"2", "3", "0", "*"},
+			hasError:  true,
+			errorKind: invalidCost,
+		},
+		{
+			input:     []string{"2", "3", "1", "up"},
+			hasError:  true,
+			errorKind: invalidDirection,
+		},
+		{
+			input:     []string{"2", "3", "1"},
+			hasError:  true,
+			errorKind: invalidNumberOfTokens,
+		},
+	}
+
+	for _, testCase := range testCases {
+		tile, err := parseTile(testCase.input)
+
+		if testCase.hasError {
+			assert.Equal(t, err.Kind(), testCase.errorKind)
+		} else {
+			assert.Equal(t, tile, testCase.expectedOutput)
+		}
+	}
+}
+
+func TestParseObstacle(t *testing.T) {
+	type testCase struct {
+		input          []string
+		expectedOutput Obstacle
+		hasError       bool
+		errorKind      parserErrorKind
+	}
+
+	testCases := []testCase{
+		{
+			input:          []string{"2", "3"},
+			expectedOutput: Obstacle{coordinate: coordinate{X: 2, Y: 3}, Width: 1, Length: 1},
+		},
+		{
+			input:          []string{"2", "3", "4x1"},
+			expectedOutput: Obstacle{coordinate: coordinate{X: 2, Y: 3}, Width: 4, Length: 1},
+		},
+		{
+			input:     []string{"2", "3", "4"},
+			hasError:  true,
+			errorKind: invalidSize,
+		},
+		{
+			input:     []string{"2", "3", "0x2"},
+			hasError:  true,
+			errorKind: invalidSize,
+		},
+		{
+			input:     []string{"2"},
+			hasError:  true,
+			errorKind: invalidNumberOfTokens,
+		},
+	}
+
+	for _, testCase := range testCases {
+		obstacle, err := parseObstacle(testCase.input)
+
+		if testCase.hasError {
+			assert.Equal(t, err.Kind(), testCase.errorKind)
+		} else {
+			assert.Equal(t, obstacle, testCase.expectedOutput)
+		}
+	}
+}
+
+func TestFindParcelClass(t *testing.T) {
 	type testCase struct {
 		input          string
 		expectedOutput weight
@@ -340,12 +680,12 @@ func TestParseWeight(t *testing.T) {
 	}
 
 	for _, testCase := range testCases {
-		nb, err := parseWeight(testCase.input)
+		class, err := findParcelClass(DefaultParcelClasses(), testCase.input)
 
 		if testCase.hasError {
 			assert.NotNil(t, err)
 		} else {
-			assert.Equal(t, nb, testCase.expectedOutput)
+			assert.Equal(t, class.Weight, testCase.expectedOutput)
 		}
 	}
 }
@@ -380,3 +720,34 @@ func TestParseNumericField(t *testing.T) {
 		}
 	}
 }
+
+func TestParseReaderAnyFormat(t *testing.T) {
+	inputs := map[Format]string{
+		TextFormat: "5 5 100\nparcel 1 1 green\nforklift 0 2\ntruck 4 4 1000 3",
+		JSONFormat: `{"width": 5, "length": 5, "cycles": 100, "parcels": [{"name": "parcel", "x": 1, "y": 1, "color": "green"}],
+			"forklifts": [{"name": "forklift", "x": 0, "y": 2}], "trucks": [{"name": "truck", "x": 4, "y": 4, "max_weight": 1000, "available": 3}]}`,
+		YAMLFormat: strings.Join([]string{
+			"width: 5",
+			"length: 5",
+			"cycles: 100",
+			"parcels: [{name: parcel, x: 1, y: 1, color: green}]",
+			"forklifts: [{name: forklift, x: 0, y: 2}]",
+			"trucks: [{name: truck, x: 4, y: 4, max_weight: 1000, available: 3}]",
+		}, "\n"),
+	}
+
+	expected, err := parseFromReader(strings.NewReader(inputs[TextFormat]))
+	assert.Nil(t, err)
+	for format, input := range inputs {
+		simul, err := ParseReader(strings.NewReader(input), AutoFormat)
+		assert.Nil(t, err, format)
+		assert.Equal(t, expected, simul, format)
+
+		simul, err = ParseReader(strings.NewReader(input), format)
+		assert.Nil(t, err, format)
+		assert.Equal(t, expected, simul, format)
+	}
+
+	_, err = ParseReader(strings.NewReader(inputs[YAMLFormat]), TextFormat)
+	assert.NotNil(t, err)
+}
@@ -1,6 +1,10 @@
 package parsing
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/adrienlucbert/gofeur/board"
+)
 
 type gridUnit uint32
 
@@ -13,6 +17,14 @@ type Simulation struct {
 	Warehouse Warehouse
 }
 
+// NewSimulation returns the simulation of an empty warehouse of the given size
+func NewSimulation(width uint32, length uint32, cycles uint32) Simulation {
+	return Simulation{
+		Cycle:     SimulationCycle(cycles),
+		Warehouse: Warehouse{Width: gridUnit(width), Length: gridUnit(length)},
+	}
+}
+
 type stringer string
 
 func (s stringer) String() string {
@@ -33,9 +45,60 @@ type Warehouse struct {
 	Parcels   []Parcel
 	Forklifts []Forklift
 	Trucks    []Truck
+	Tiles     []Tile
+	Obstacles []Obstacle
+
+	// Classes is the parcel classes catalogue, nil if the input file doesn't
+	// declare one, in which case the default one is used
+	Classes []ParcelClass
+}
+
+// ParcelClasses returns the parcel classes catalogue of the warehouse
+func (warehouse Warehouse) ParcelClasses() []ParcelClass {
+	if len(warehouse.Classes) == 0 {
+		return DefaultParcelClasses()
+	}
+	return warehouse.Classes
+}
+
+// ParcelClassOf returns the class of a parcel of the warehouse, found by name,
+// or by weight for parcels without a color
+func (warehouse Warehouse) ParcelClassOf(parcel Parcel) (ParcelClass, bool) {
+	classes := warehouse.ParcelClasses()
+	if class, err := findParcelClass(classes, parcel.Color); err == nil {
+		return class, true
+	}
+	if parcel.Color == "" {
+		for _, class := range classes {
+			if class.Weight == parcel.Weight {
+				return class, true
+			}
+		}
+	}
+	return ParcelClass{}, false
+}
+
+// ParcelClass represents a category of parcels, which all weigh the same and
+// are displayed the same way. Color is a color name, such as red, or a rrggbb
+// hexadecimal color code, such as ff8800, without # as it starts comments.
+type ParcelClass struct {
+	Name   string
+	Weight weight
+	Glyph  rune
+	Color  string
 }
 
-// Parcel represents a parsed parcel
+// DefaultParcelClasses returns the parcel classes used when an input file
+// doesn't declare any
+func DefaultParcelClasses() []ParcelClass {
+	return []ParcelClass{
+		{Name: "yellow", Weight: yellow, Glyph: '1', Color: "yellow"},
+		{Name: "green", Weight: green, Glyph: '2', Color: "green"},
+		{Name: "blue", Weight: blue, Glyph: '3', Color: "blue"},
+	}
+}
+
+// Parcel represents a parsed parcel, which Color is the name of its class
 type Parcel struct {
 	Name string
 	coordinate
@@ -43,6 +106,11 @@ type Parcel struct {
 	Weight weight
 }
 
+// NewParcel returns a parcel of the given class
+func NewParcel(name string, x uint32, y uint32, class ParcelClass) Parcel {
+	return Parcel{Name: name, coordinate: coordinate{X: gridUnit(x), Y: gridUnit(y)}, Color: class.Name, Weight: class.Weight}
+}
+
 func (parcel Parcel) stringerName() stringer {
 	return stringer(parcel.Name)
 }
@@ -61,6 +129,11 @@ type Forklift struct {
 	coordinate
 }
 
+// NewForklift returns a forklift at the given coordinates
+func NewForklift(name string, x uint32, y uint32) Forklift {
+	return Forklift{Name: name, coordinate: coordinate{X: gridUnit(x), Y: gridUnit(y)}}
+}
+
 func (forklift Forklift) stringerName() stringer {
 	return stringer(forklift.Name)
 }
@@ -81,6 +154,11 @@ type Truck struct {
 	Available uint32
 }
 
+// NewTruck returns a truck at the given coordinates
+func NewTruck(name string, x uint32, y uint32, maxWeight uint32, available uint32) Truck {
+	return Truck{Name: name, coordinate: coordinate{X: gridUnit(x), Y: gridUnit(y)}, MaxWeight: weight(maxWeight), Available: available}
+}
+
 func (truck Truck) stringerName() stringer {
 	return stringer(truck.Name)
 }
@@ -93,6 +171,39 @@ func (truck Truck) coord() coordinate {
 	return truck.coordinate
 }
 
+// Tile represents the parsed properties of a warehouse cell: the number of
+// rounds it takes to enter it, and the directions it can be entered from
+type Tile struct {
+	coordinate
+	Cost       uint32
+	Directions board.Direction
+}
+
+// Obstacle represents a parsed rectangle of permanently blocked cells, such as
+// a wall, a rack or a pillar, which top-left cell is at its coordinates
+type Obstacle struct {
+	coordinate
+	Width  gridUnit
+	Length gridUnit
+}
+
+// NewObstacle returns an obstacle which top-left cell is at the given
+// coordinates
+func NewObstacle(x uint32, y uint32, width uint32, length uint32) Obstacle {
+	return Obstacle{coordinate: coordinate{X: gridUnit(x), Y: gridUnit(y)}, Width: gridUnit(width), Length: gridUnit(length)}
+}
+
+// Cells returns the coordinates of every cell covered by the obstacle
+func (obstacle Obstacle) Cells() []coordinate {
+	cells := make([]coordinate, 0, obstacle.Width*obstacle.Length)
+	for y := obstacle.Y; y < obstacle.Y+obstacle.Length; y++ {
+		for x := obstacle.X; x < obstacle.X+obstacle.Width; x++ {
+			cells = append(cells, coordinate{X: x, Y: y})
+		}
+	}
+	return cells
+}
+
 type weight uint32
 
 type coordinate struct {
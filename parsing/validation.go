@@ -11,44 +11,148 @@ var (
 	errAtLeastOneTruck    = errors.New("Please provide at least one truck")
 )
 
-// VerifySimulationValidity return an error if one of the following condition is meet:
+// VerifySimulationValidity return a ValidationError if one of the following condition is meet:
 //
 //   - there is no forklift in `simulation`
 //   - there is no truck in `simulation`
+//   - an entity is out of the warehouse
 //   - two entities are on the same grid cell
 //   - two entities bears the same name
+//   - a tile is out of the warehouse, or described twice
+//   - an obstacle is out of the warehouse, or an entity is on an obstacle
+//   - two parcel classes bear the same name
 func VerifySimulationValidity(simulation Simulation) error {
-	if len(simulation.Warehouse.Forklifts) == 0 {
-		return errAtLeastOneForklift
+	violations := validate(simulation)
+	if len(violations) > 0 {
+		return newValidationError(violations)
 	}
+	return nil
+}
 
-	if len(simulation.Warehouse.Trucks) == 0 {
-		return errAtLeastOneTruck
+// InvalidEntity is something declared in a simulation that breaks a
+// validation rule. Kind is parcel, forklift, truck, tile, obstacle, class, or
+// warehouse for rules about the whole warehouse, in which case Name and the
+// coordinates are empty. Tiles and obstacles have no name, and classes no
+// coordinates. Code identifies the rule, as in diagnostics, and Rule describes
+// it.
+type InvalidEntity struct {
+	Kind string
+	Name string
+	coordinate
+	Code string
+	Rule string
+}
+
+// ValidationError lists every entity of a simulation that breaks a validation
+// rule, in the order rules are checked. Entities breaking a rule together,
+// such as stacked entities, are all listed.
+type ValidationError struct {
+	Entities []InvalidEntity
+
+	violations []violation
+}
+
+func (err ValidationError) Error() string {
+	if len(err.violations) == 1 {
+		return err.violations[0].err.Error()
+	}
+	messages := make([]string, 0, len(err.violations))
+	for _, v := range err.violations {
+		messages = append(messages, "  "+v.message())
 	}
+	return fmt.Sprintf("Error found %d invalid declarations:\n%s", len(err.violations), strings.Join(messages, "\n"))
+}
+
+// validationRules describes the rules validation checks, by code
+var validationRules = map[string]string{
+	codeNoForklift:         "the warehouse holds at least one forklift",
+	codeNoTruck:            "the warehouse holds at least one truck",
+	codeTooSmallWarehouse:  "the warehouse has at least 2 cells",
+	codeTruckNotOnSide:     "trucks are on a side of the warehouse",
+	codeOutOfBound:         "entities are within the warehouse",
+	codeStackedEntities:    "entities are on distinct cells",
+	codeDuplicatedName:     "entities have distinct names",
+	codeTileOutOfBound:     "tiles are within the warehouse",
+	codeDuplicatedTile:     "tiles are described once",
+	codeObstacleOutOfBound: "obstacles are within the warehouse",
+	codeEntityOnObstacle:   "entities aren't on obstacles",
+	codeDuplicatedClass:    "parcel classes have distinct names",
+}
 
-	err := checkWarehouseSize(simulation.Warehouse)
-	if err != nil {
-		return err
+func newValidationError(violations []violation) ValidationError {
+	err := ValidationError{violations: violations}
+	for _, v := range violations {
+		offenders := v.offenders
+		if len(offenders) == 0 {
+			offenders = []sourceKey{v.source}
+		}
+		for _, offender := range offenders {
+			err.Entities = append(err.Entities, InvalidEntity{
+				Kind:       offender.kind,
+				Name:       offender.name,
+				coordinate: offender.coord,
+				Code:       v.code,
+				Rule:       validationRules[v.code],
+			})
+		}
 	}
+	return err
+}
+
+// violation is a failed validation or lint check, along with the entity it is
+// about, if any, so that it can be traced back to the line it was declared on.
+// detail describes the violation on a single line, when err doesn't, and
+// offenders lists the entities breaking the rule together, when source isn't
+// the only one.
+type violation struct {
+	severity  Severity
+	code      string
+	err       error
+	detail    string
+	source    sourceKey
+	offenders []sourceKey
+}
 
-	err = ensureTrucksAreOnAWarehouseSide(simulation.Warehouse.Trucks, simulation.Warehouse)
-	if err != nil {
-		return err
+func (v violation) message() string {
+	if v.detail != "" {
+		return v.detail
 	}
+	return v.err.Error()
+}
 
-	entities := makeEntitiesArray(simulation)
+// diagnostic turns the violation into a diagnostic, which line is looked up in
+// sources, if any
+func (v violation) diagnostic(file string, sources sourceMap) Diagnostic {
+	d := Diagnostic{File: file, Severity: v.severity, Code: v.code, Message: v.message()}
+	if line, ok := sources[v.source]; ok {
+		d.Line, d.Column = line, 1
+	}
+	return d
+}
+
+// validate runs every validation check, and returns all the violations found
+func validate(simulation Simulation) []violation {
+	violations := []violation{}
 
-	err = checkForOutOfWarehouseBoundEntity(entities, simulation.Warehouse)
-	if err != nil {
-		return err
+	if len(simulation.Warehouse.Forklifts) == 0 {
+		violations = append(violations, violation{code: codeNoForklift, err: errAtLeastOneForklift, source: warehouseSourceKey})
 	}
 
-	err = ensureNoStackedEntities(entities)
-	if err != nil {
-		return err
+	if len(simulation.Warehouse.Trucks) == 0 {
+		violations = append(violations, violation{code: codeNoTruck, err: errAtLeastOneTruck, source: warehouseSourceKey})
 	}
 
-	return ensureForDuplicatedEntitiyName(entities)
+	violations = append(violations, checkWarehouseSize(simulation.Warehouse)...)
+	violations = append(violations, ensureTrucksAreOnAWarehouseSide(simulation.Warehouse.Trucks, simulation.Warehouse)...)
+
+	entities := makeEntitiesArray(simulation)
+
+	violations = append(violations, checkForOutOfWarehouseBoundEntity(entities, simulation.Warehouse)...)
+	violations = append(violations, ensureNoStackedEntities(entities)...)
+	violations = append(violations, ensureForDuplicatedEntitiyName(entities)...)
+	violations = append(violations, checkClasses(simulation.Warehouse)...)
+	violations = append(violations, checkTiles(simulation.Warehouse)...)
+	return append(violations, checkObstacles(entities, simulation.Warehouse)...)
 }
 
 type tooSmallWarehouseError struct {
@@ -59,12 +163,12 @@ func (err tooSmallWarehouseError) Error() string {
 	return fmt.Sprintf("too small warehouse (%d)", err.size)
 }
 
-func checkWarehouseSize(warehouse Warehouse) error {
+func checkWarehouseSize(warehouse Warehouse) []violation {
 	size := warehouse.Length * warehouse.Width
 	var minimumWarehouseSize gridUnit = 2
 
 	if size < minimumWarehouseSize {
-		return tooSmallWarehouseError{size: size}
+		return []violation{{code: codeTooSmallWarehouse, err: tooSmallWarehouseError{size: size}, source: warehouseSourceKey}}
 	}
 	return nil
 }
@@ -85,7 +189,7 @@ func (err notOnSideTrucksError) Error() string {
 	return output
 }
 
-func ensureTrucksAreOnAWarehouseSide(trucks []Truck, warehouse Warehouse) error {
+func ensureTrucksAreOnAWarehouseSide(trucks []Truck, warehouse Warehouse) []violation {
 	min := coordinate{}
 	max := coordinate{X: warehouse.Width - 1, Y: warehouse.Length - 1}
 
@@ -100,10 +204,16 @@ func ensureTrucksAreOnAWarehouseSide(trucks []Truck, warehouse Warehouse) error
 		}
 	}
 
-	if len(errTrucks) > 0 {
-		return notOnSideTrucksError{trucks: errTrucks}
+	violations := make([]violation, 0, len(errTrucks))
+	for _, truck := range errTrucks {
+		violations = append(violations, violation{
+			code:   codeTruckNotOnSide,
+			err:    notOnSideTrucksError{trucks: errTrucks},
+			detail: fmt.Sprintf("The truck named %s is not on a side of the warehouse", truck.Name),
+			source: entitySourceKey(truck),
+		})
 	}
-	return nil
+	return violations
 }
 
 func makeEntitiesArray(simulation Simulation) []entity {
@@ -133,16 +243,17 @@ func (err outOfBoundError) Error() string {
 	return fmt.Sprintf("The %s named %s is out of bound", err.entity.kind(), err.entity.stringerName())
 }
 
-func checkForOutOfWarehouseBoundEntity(entities []entity, warehouse Warehouse) error {
+func checkForOutOfWarehouseBoundEntity(entities []entity, warehouse Warehouse) []violation {
+	violations := []violation{}
 	for _, entity := range entities {
 		coord := entity.coord()
 
-		if !(coord.X < warehouse.Width && coord.Y < warehouse.Width) {
-			return outOfBoundError{entity: entity}
+		if !(coord.X < warehouse.Width && coord.Y < warehouse.Length) {
+			violations = append(violations, violation{code: codeOutOfBound, err: outOfBoundError{entity: entity}, source: entitySourceKey(entity)})
 		}
 	}
 
-	return nil
+	return violations
 }
 
 type stackedEntitiesError struct {
@@ -153,12 +264,18 @@ func (err stackedEntitiesError) Error() string {
 	return fmt.Sprintf("Error found stacked entities:\n%s", err.err.Error())
 }
 
-func ensureNoStackedEntities(entities []entity) error {
-	err := hasEntityPropertyDup(entities, entity.coord)
-	if err != nil {
-		return stackedEntitiesError{err: err}
+func ensureNoStackedEntities(entities []entity) []violation {
+	violations := []violation{}
+	for _, dup := range findEntityPropertyDups(entities, entity.coord) {
+		violations = append(violations, violation{
+			code:      codeStackedEntities,
+			err:       stackedEntitiesError{err: dup},
+			detail:    fmt.Sprintf("Stacked entities at %s", dup.Error()),
+			source:    entitySourceKey(dup.entities[len(dup.entities)-1]),
+			offenders: dup.sourceKeys(),
+		})
 	}
-	return nil
+	return violations
 }
 
 type dupEntityNameError struct {
@@ -169,62 +286,176 @@ func (err dupEntityNameError) Error() string {
 	return fmt.Sprintf("Error found duplicated entities name:\n%s", err.err.Error())
 }
 
-func ensureForDuplicatedEntitiyName(entities []entity) error {
-	err := hasEntityPropertyDup(entities, entity.stringerName)
-	if err != nil {
-		return dupEntityNameError{err: err}
+func ensureForDuplicatedEntitiyName(entities []entity) []violation {
+	violations := []violation{}
+	for _, dup := range findEntityPropertyDups(entities, entity.stringerName) {
+		violations = append(violations, violation{
+			code:      codeDuplicatedName,
+			err:       dupEntityNameError{err: dup},
+			detail:    fmt.Sprintf("Duplicated entities name %s", dup.Error()),
+			source:    entitySourceKey(dup.entities[len(dup.entities)-1]),
+			offenders: dup.sourceKeys(),
+		})
 	}
-	return nil
+	return violations
 }
 
-type dupEntityError[T interface {
-	fmt.Stringer
-	comparable
-}] struct {
-	propertiesEntities map[T][]*entity
+type dupEntityError[T fmt.Stringer] struct {
+	property T
+	entities []entity
 }
 
 func (err dupEntityError[T]) Error() string {
-	output := make([]string, 0, len(err.propertiesEntities))
+	errEntities := make([]string, 0, len(err.entities))
+	for _, entity := range err.entities {
+		errEntities = append(errEntities, fmt.Sprintf("%s: %s", entity.kind(), entity.stringerName()))
+	}
 
-	for property, entities := range err.propertiesEntities {
-		errEntities := make([]string, 0, len(entities))
-		for _, entity := range entities {
-			kind := (*entity).kind()
-			name := (*entity).stringerName()
+	return fmt.Sprintf("%s: %s", err.property, strings.Join(errEntities, ", "))
+}
 
-			errEntities = append(errEntities, fmt.Sprintf("%s: %s", kind, name))
-		}
-		output = append(output, fmt.Sprintf("%s: %s", property, strings.Join(errEntities, ", ")))
+func (err dupEntityError[T]) sourceKeys() []sourceKey {
+	keys := make([]sourceKey, 0, len(err.entities))
+	for _, entity := range err.entities {
+		keys = append(keys, entitySourceKey(entity))
 	}
-
-	return strings.Join(output, "\n")
+	return keys
 }
 
-func hasEntityPropertyDup[T interface {
+// findEntityPropertyDups returns the groups of entities that share the same
+// property, in the order their first entity appears in
+func findEntityPropertyDups[T interface {
 	comparable
 	fmt.Stringer
 }](entities []entity, propertyGettor func(entity) T,
-) error {
-	board := make(map[T][]*entity, len(entities))
+) []dupEntityError[T] {
+	groups := make(map[T]int, len(entities))
+	dups := []dupEntityError[T]{}
+
+	for _, entity := range entities {
+		property := propertyGettor(entity)
 
-	for i := range entities {
-		property := propertyGettor(entities[i])
+		i, ok := groups[property]
+		if !ok {
+			i = len(dups)
+			groups[property] = i
+			dups = append(dups, dupEntityError[T]{property: property})
+		}
+		dups[i].entities = append(dups[i].entities, entity)
+	}
 
-		if board[property] == nil {
-			board[property] = make([]*entity, 0, 1)
+	found := dups[:0]
+	for _, dup := range dups {
+		if len(dup.entities) > 1 {
+			found = append(found, dup)
 		}
-		board[property] = append(board[property], &entities[i])
 	}
+	return found
+}
+
+type dupClassError struct {
+	class ParcelClass
+}
 
-	for coord, entities := range board {
-		if len(entities) == 1 {
-			delete(board, coord)
+func (err dupClassError) Error() string {
+	return fmt.Sprintf("The parcel class %s is declared more than once", err.class.Name)
+}
+
+// checkClasses ensures parcel class names are unique, regardless of their
+// case, as parcels can refer to classes in upper case
+func checkClasses(warehouse Warehouse) []violation {
+	violations := []violation{}
+	seen := make(map[string]bool, len(warehouse.Classes))
+
+	for _, class := range warehouse.Classes {
+		name := strings.ToLower(class.Name)
+		if seen[name] {
+			violations = append(violations, violation{
+				code:   codeDuplicatedClass,
+				err:    dupClassError{class: class},
+				source: sourceKey{kind: "class", name: class.Name},
+			})
 		}
+		seen[name] = true
 	}
 
-	if len(board) != 0 {
-		return dupEntityError[T]{propertiesEntities: board}
+	return violations
+}
+
+type outOfBoundTileError struct {
+	tile Tile
+}
+
+func (err outOfBoundTileError) Error() string {
+	return fmt.Sprintf("The tile %s is out of bound", err.tile.coordinate)
+}
+
+type dupTileError struct {
+	tile Tile
+}
+
+func (err dupTileError) Error() string {
+	return fmt.Sprintf("The tile %s is described more than once", err.tile.coordinate)
+}
+
+func checkTiles(warehouse Warehouse) []violation {
+	violations := []violation{}
+	seen := make(map[coordinate]bool, len(warehouse.Tiles))
+
+	for _, tile := range warehouse.Tiles {
+		source := sourceKey{kind: "tile", coord: tile.coordinate}
+		if !(tile.X < warehouse.Width && tile.Y < warehouse.Length) {
+			violations = append(violations, violation{code: codeTileOutOfBound, err: outOfBoundTileError{tile: tile}, source: source})
+		} else if seen[tile.coordinate] {
+			violations = append(violations, violation{code: codeDuplicatedTile, err: dupTileError{tile: tile}, source: source})
+		}
+		seen[tile.coordinate] = true
 	}
-	return nil
+
+	return violations
+}
+
+type outOfBoundObstacleError struct {
+	obstacle Obstacle
+}
+
+func (err outOfBoundObstacleError) Error() string {
+	return fmt.Sprintf("The %dx%d obstacle at %s is out of bound", err.obstacle.Width, err.obstacle.Length, err.obstacle.coordinate)
+}
+
+type entityOnObstacleError struct {
+	entity entity
+}
+
+func (err entityOnObstacleError) Error() string {
+	return fmt.Sprintf("The %s named %s is on an obstacle", err.entity.kind(), err.entity.stringerName())
+}
+
+func checkObstacles(entities []entity, warehouse Warehouse) []violation {
+	violations := []violation{}
+	cells := map[coordinate]bool{}
+
+	for _, obstacle := range warehouse.Obstacles {
+		// compared without adding, which could wrap around
+		if obstacle.Width > warehouse.Width || obstacle.X > warehouse.Width-obstacle.Width ||
+			obstacle.Length > warehouse.Length || obstacle.Y > warehouse.Length-obstacle.Length {
+			violations = append(violations, violation{
+				code:   codeObstacleOutOfBound,
+				err:    outOfBoundObstacleError{obstacle: obstacle},
+				source: sourceKey{kind: "obstacle", coord: obstacle.coordinate},
+			})
+			continue
+		}
+		for _, cell := range obstacle.Cells() {
+			cells[cell] = true
+		}
+	}
+
+	for _, entity := range entities {
+		if cells[entity.coord()] {
+			violations = append(violations, violation{code: codeEntityOnObstacle, err: entityOnObstacleError{entity: entity}, source: entitySourceKey(entity)})
+		}
+	}
+
+	return violations
 }
@@ -1,6 +1,8 @@
 package parsing
 
 import (
+	"errors"
+	"math"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -104,6 +106,134 @@ func TestVerifySimulationValidity(t *testing.T) {
 			},
 			hasError: false,
 		},
+		{
+			input: Simulation{
+				Warehouse: Warehouse{
+					Width:  2,
+					Length: 1,
+					Forklifts: []Forklift{
+						{Name: "forklift", coordinate: coordinate{X: 1}},
+					},
+					Trucks: []Truck{
+						{Name: "truck"},
+					},
+					Tiles: []Tile{
+						{coordinate: coordinate{X: 1, Y: 1}, Cost: 2},
+					},
+				},
+			},
+			hasError: true,
+		},
+		{
+			input: Simulation{
+				Warehouse: Warehouse{
+					Width:  2,
+					Length: 1,
+					Forklifts: []Forklift{
+						{Name: "forklift", coordinate: coordinate{X: 1}},
+					},
+					Trucks: []Truck{
+						{Name: "truck"},
+					},
+					Tiles: []Tile{
+						{coordinate: coordinate{X: 1}, Cost: 2},
+						{coordinate: coordinate{X: 1}, Cost: 3},
+					},
+				},
+			},
+			hasError: true,
+		},
+		{
+			input: Simulation{
+				Warehouse: Warehouse{
+					Width:  3,
+					Length: 3,
+					Forklifts: []Forklift{
+						{Name: "forklift", coordinate: coordinate{X: 1}},
+					},
+					Trucks: []Truck{
+						{Name: "truck"},
+					},
+					Obstacles: []Obstacle{
+						{coordinate: coordinate{X: 1, Y: 1}, Width: 3, Length: 1},
+					},
+				},
+			},
+			hasError: true,
+		},
+		{
+			input: Simulation{
+				Warehouse: Warehouse{
+					Width:  3,
+					Length: 3,
+					Forklifts: []Forklift{
+						{Name: "forklift", coordinate: coordinate{X: 1, Y: 2}},
+					},
+					Trucks: []Truck{
+						{Name: "truck"},
+					},
+					Obstacles: []Obstacle{
+						{coordinate: coordinate{X: 1, Y: 1}, Width: 1, Length: 2},
+					},
+				},
+			},
+			hasError: true,
+		},
+		{
+			input: Simulation{
+				Warehouse: Warehouse{
+					Width:  3,
+					Length: 3,
+					Forklifts: []Forklift{
+						{Name: "forklift", coordinate: coordinate{X: 1}},
+					},
+					Trucks: []Truck{
+						{Name: "truck"},
+					},
+					Obstacles: []Obstacle{
+						{coordinate: coordinate{X: 2, Y: 2}, Width: math.MaxUint32, Length: 1},
+					},
+				},
+			},
+			hasError: true,
+		},
+		{
+			input: Simulation{
+				Warehouse: Warehouse{
+					Width:  3,
+					Length: 3,
+					Forklifts: []Forklift{
+						{Name: "forklift", coordinate: coordinate{X: 1}},
+					},
+					Trucks: []Truck{
+						{Name: "truck"},
+					},
+					Obstacles: []Obstacle{
+						{coordinate: coordinate{X: 2, Y: 2}, Width: 1, Length: math.MaxUint32},
+					},
+				},
+			},
+			hasError: true,
+		},
+		{
+			input: Simulation{
+				Warehouse: Warehouse{
+					Width:  3,
+					Length: 3,
+					Forklifts: []Forklift{
+						{Name: "forklift", coordinate: coordinate{X: 1, Y: 1}},
+					},
+					Trucks: []Truck{
+						{Name: "truck"},
+					},
+					Classes: []ParcelClass{
+						{Name: "fragile", Weight: 50, Glyph: 'f', Color: "pink"},
+						{Name: "FRAGILE", Weight: 60, Glyph: 'F', Color: "red"},
+					},
+				},
+			},
+			hasError: true,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -116,3 +246,99 @@ func TestVerifySimulationValidity(t *testing.T) {
 		}
 	}
 }
+
+func TestValidationError(t *testing.T) {
+	type testCase struct {
+		name      string
+		warehouse Warehouse
+		entities  []InvalidEntity
+	}
+
+	truck := Truck{Name: "truck", MaxWeight: 500}
+	testCases := []testCase{
+		{
+			name: "long warehouse",
+			warehouse: Warehouse{
+				Width:     3,
+				Length:    5,
+				Forklifts: []Forklift{{Name: "forklift", coordinate: coordinate{X: 1, Y: 4}}},
+				Trucks:    []Truck{truck},
+				Tiles:     []Tile{{coordinate: coordinate{X: 2, Y: 4}, Cost: 2}},
+			},
+		},
+		{
+			name: "wide warehouse",
+			warehouse: Warehouse{
+				Width:     5,
+				Length:    3,
+				Forklifts: []Forklift{{Name: "forklift", coordinate: coordinate{X: 1, Y: 4}}},
+				Trucks:    []Truck{truck},
+				Tiles:     []Tile{{coordinate: coordinate{X: 4, Y: 2}, Cost: 2}},
+			},
+			entities: []InvalidEntity{
+				{Kind: "forklift", Name: "forklift", coordinate: coordinate{X: 1, Y: 4}, Code: codeOutOfBound, Rule: validationRules[codeOutOfBound]},
+			},
+		},
+		{
+			name: "out of bound and stacked entities",
+			warehouse: Warehouse{
+				Width:     5,
+				Length:    3,
+				Parcels:   []Parcel{{Name: "parcel", coordinate: coordinate{X: 5, Y: 0}, Weight: 100}},
+				Forklifts: []Forklift{{Name: "forklift", coordinate: coordinate{X: 0, Y: 2}}},
+				Trucks:    []Truck{{Name: "truck", coordinate: coordinate{X: 0, Y: 2}}},
+			},
+			entities: []InvalidEntity{
+				{Kind: "parcel", Name: "parcel", coordinate: coordinate{X: 5, Y: 0}, Code: codeOutOfBound, Rule: validationRules[codeOutOfBound]},
+				{Kind: "forklift", Name: "forklift", coordinate: coordinate{X: 0, Y: 2}, Code: codeStackedEntities, Rule: validationRules[codeStackedEntities]},
+				{Kind: "truck", Name: "truck", coordinate: coordinate{X: 0, Y: 2}, Code: codeStackedEntities, Rule: validationRules[codeStackedEntities]},
+			},
+		},
+		{
+			name: "duplicated names",
+			warehouse: Warehouse{
+				Width:     2,
+				Length:    6,
+				Forklifts: []Forklift{{Name: "entity", coordinate: coordinate{X: 1, Y: 5}}},
+				Trucks:    []Truck{{Name: "entity", coordinate: coordinate{X: 0, Y: 3}}},
+			},
+			entities: []InvalidEntity{
+				{Kind: "forklift", Name: "entity", coordinate: coordinate{X: 1, Y: 5}, Code: codeDuplicatedName, Rule: validationRules[codeDuplicatedName]},
+				{Kind: "truck", Name: "entity", coordinate: coordinate{X: 0, Y: 3}, Code: codeDuplicatedName, Rule: validationRules[codeDuplicatedName]},
+			},
+		},
+		{
+			name: "no forklift and out of bound tile",
+			warehouse: Warehouse{
+				Width:  4,
+				Length: 2,
+				Trucks: []Truck{truck},
+				Tiles:  []Tile{{coordinate: coordinate{X: 1, Y: 3}, Cost: 2}},
+			},
+			entities: []InvalidEntity{
+				{Kind: "warehouse", Code: codeNoForklift, Rule: validationRules[codeNoForklift]},
+				{Kind: "tile", coordinate: coordinate{X: 1, Y: 3}, Code: codeTileOutOfBound, Rule: validationRules[codeTileOutOfBound]},
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		err := VerifySimulationValidity(Simulation{Warehouse: testCase.warehouse})
+		if testCase.entities == nil {
+			assert.Nil(t, err, testCase.name)
+			continue
+		}
+		var validationErr ValidationError
+		if assert.True(t, errors.As(err, &validationErr), testCase.name) {
+			assert.Equal(t, testCase.entities, validationErr.Entities, testCase.name)
+		}
+	}
+}
+
+func TestValidationErrorMessage(t *testing.T) {
+	err := VerifySimulationValidity(Simulation{Warehouse: Warehouse{Width: 1, Length: 1}})
+	assert.Equal(t, "Error found 3 invalid declarations:\n  Please provide at least a forklift\n  Please provide at least one truck\n  too small warehouse (1)", err.Error())
+
+	err = VerifySimulationValidity(Simulation{Warehouse: Warehouse{Width: 2, Length: 1, Trucks: []Truck{{Name: "truck"}}}})
+	assert.Equal(t, errAtLeastOneForklift.Error(), err.Error())
+}
@@ -0,0 +1,274 @@
+package pathfinding
+
+import (
+	"container/heap"
+
+	"github.com/adrienlucbert/gofeur/board"
+	"github.com/adrienlucbert/gofeur/pkg"
+)
+
+type reservationKey struct {
+	pos   pkg.Vector
+	round uint
+}
+
+type parking struct {
+	pos  pkg.Vector
+	from uint
+}
+
+// ReservationTable records which tiles agents occupy at which round, so that
+// they can plan paths around each other's future positions.
+type ReservationTable struct {
+	reservations map[reservationKey]string
+	byOwner      map[string][]reservationKey
+	parked       map[pkg.Vector]string
+	parkings     map[string]parking
+}
+
+// NewReservationTable initializes an empty reservation table
+func NewReservationTable() *ReservationTable {
+	return &ReservationTable{
+		reservations: map[reservationKey]string{},
+		byOwner:      map[string][]reservationKey{},
+		parked:       map[pkg.Vector]string{},
+		parkings:     map[string]parking{},
+	}
+}
+
+// Reserve reserves a tile at the given round
+func (t *ReservationTable) Reserve(pos pkg.Vector, round uint, owner string) {
+	key := reservationKey{pos: pos, round: round}
+	t.reservations[key] = owner
+	t.byOwner[owner] = append(t.byOwner[owner], key)
+}
+
+// Park reserves a tile for every round starting from the given one, in place
+// of the owner's previous parking if any
+func (t *ReservationTable) Park(pos pkg.Vector, from uint, owner string) {
+	if p, ok := t.parkings[owner]; ok && t.parked[p.pos] == owner {
+		delete(t.parked, p.pos)
+	}
+	t.parked[pos] = owner
+	t.parkings[owner] = parking{pos: pos, from: from}
+}
+
+// Hold reserves a tile at the given round and the next one only, for an agent
+// waiting on it until it plans again
+func (t *ReservationTable) Hold(pos pkg.Vector, round uint, owner string) {
+	t.Reserve(pos, round, owner)
+	t.Reserve(pos, round+1, owner)
+}
+
+// ReservePath reserves the tiles an agent goes through when following path
+// from start, one tile per round, then parks it on the last tile of the path
+func (t *ReservationTable) ReservePath(start pkg.Vector, path []pkg.Vector, round uint, owner string) {
+	t.Reserve(start, round, owner)
+	last := start
+	for i, pos := range path {
+		t.Reserve(pos, round+uint(i)+1, owner)
+		last = pos
+	}
+	t.Park(last, round+uint(len(path)), owner)
+}
+
+// Release cancels all reservations of the given owner
+func (t *ReservationTable) Release(owner string) {
+	for _, key := range t.byOwner[owner] {
+		if t.reservations[key] == owner {
+			delete(t.reservations, key)
+		}
+	}
+	delete(t.byOwner, owner)
+	if p, ok := t.parkings[owner]; ok {
+		if t.parked[p.pos] == owner {
+			delete(t.parked, p.pos)
+		}
+		delete(t.parkings, owner)
+	}
+}
+
+// Prune drops reservations made for rounds before the given one
+func (t *ReservationTable) Prune(round uint) {
+	for owner, keys := range t.byOwner {
+		kept := keys[:0]
+		for _, key := range keys {
+			if key.round >= round {
+				kept = append(kept, key)
+			} else if t.reservations[key] == owner {
+				delete(t.reservations, key)
+			}
+		}
+		if len(kept) == 0 {
+			delete(t.byOwner, owner)
+		} else {
+			t.byOwner[owner] = kept
+		}
+	}
+}
+
+// Owner returns who reserved the given tile at the given round, if anyone
+func (t *ReservationTable) Owner(pos pkg.Vector, round uint) (string, bool) {
+	if owner, ok := t.reservations[reservationKey{pos: pos, round: round}]; ok {
+		return owner, true
+	}
+	if owner, ok := t.parked[pos]; ok && t.parkings[owner].from <= round {
+		return owner, true
+	}
+	return "", false
+}
+
+// IsReserved returns whether the given tile is reserved by someone other than
+// owner at the given round
+func (t *ReservationTable) IsReserved(pos pkg.Vector, round uint, owner string) bool {
+	other, ok := t.Owner(pos, round)
+	return ok && other != owner
+}
+
+// isReservedFrom returns whether the given tile is reserved by someone other
+// than owner at any round starting from the given one
+func (t *ReservationTable) isReservedFrom(pos pkg.Vector, round uint, owner string) bool {
+	if other, ok := t.parked[pos]; ok && other != owner {
+		return true
+	}
+	for key, other := range t.reservations {
+		if key.pos == pos && key.round >= round && other != owner {
+			return true
+		}
+	}
+	return false
+}
+
+// cooperativeSlack is the number of extra rounds a cooperative path may take
+// compared to the path ignoring other agents, before giving up
+const cooperativeSlack = 16
+
+type spaceTimeNode struct {
+	parent *spaceTimeNode
+	pos    pkg.Vector
+	round  uint
+	f      uint
+	index  int
+}
+
+type spaceTimeQueue []*spaceTimeNode
+
+func (q spaceTimeQueue) Len() int { return len(q) }
+
+func (q spaceTimeQueue) Less(i, j int) bool {
+	if q[i].f != q[j].f {
+		return q[i].f < q[j].f
+	}
+	return q[i].index < q[j].index
+}
+
+func (q spaceTimeQueue) Swap(i, j int) { q[i], q[j] = q[j], q[i] }
+
+func (q *spaceTimeQueue) Push(x any) { *q = append(*q, x.(*spaceTimeNode)) }
+
+func (q *spaceTimeQueue) Pop() any {
+	old := *q
+	n := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return n
+}
+
+// ResolveCooperative returns, if possible, a series of moves that form a path
+// between start and end, starting at the given round, that doesn't go through
+// tiles reserved by other agents. The path may hold the same position several
+// times in a row, meaning the agent has to wait. The end tile is never
+// checked against reservations, and the agent must be able to stay on the
+// tile preceding it for every following round. ErrPathReserved is returned
+// when only other agents' reservations prevent finding a path.
+//
+// An agent may not enter a tile another agent occupies at the previous round,
+// nor occupy a tile another agent enters at the next round, which also
+// prevents agents from swapping tiles. Entering a tile which cost is greater
+// than 1 takes as many rounds, the agent waiting on its current tile first.
+func ResolveCooperative(maze *board.Board, start pkg.Vector, end pkg.Vector, round uint, table *ReservationTable, owner string) ([]pkg.Vector, error) {
+	static, err := Resolve(maze, start, end)
+	if err != nil || start == end {
+		return static, err
+	}
+	var staticCost uint
+	for _, pos := range static[:len(static)-1] {
+		staticCost += maze.At(uint(pos.X), uint(pos.Y)).EnterCost()
+	}
+	horizon := round + 2*staticCost + cooperativeSlack
+
+	type state struct {
+		pos   pkg.Vector
+		round uint
+	}
+	closed := map[state]bool{}
+	count := 0
+	open := &spaceTimeQueue{{pos: start, round: round, f: round + uint(start.ManhattanDistance(end))}}
+	for open.Len() > 0 {
+		current := heap.Pop(open).(*spaceTimeNode)
+		if closed[state{current.pos, current.round}] {
+			continue
+		}
+		closed[state{current.pos, current.round}] = true
+
+		for _, direction := range []pkg.Vector{{X: 0, Y: 1}, {X: 0, Y: -1}, {X: 1, Y: 0}, {X: -1, Y: 0}, {X: 0, Y: 0}} {
+			next := current.pos.Add(direction)
+			if next == end && direction != (pkg.Vector{}) {
+				if table.isReservedFrom(current.pos, current.round, owner) {
+					continue
+				}
+				return reconstructSpaceTimePath(&spaceTimeNode{parent: current, pos: next}), nil
+			}
+			parent := current
+			if next != current.pos {
+				if !isPositionAvailable(maze, next) || !maze.At(uint(next.X), uint(next.Y)).Allows(direction) {
+					continue
+				}
+				parent = waitBeforeEntering(current, maze.At(uint(next.X), uint(next.Y)).EnterCost(), table, owner)
+				if parent == nil {
+					continue
+				}
+			}
+			nextRound := parent.round + 1
+			if nextRound > horizon || closed[state{next, nextRound}] {
+				continue
+			}
+			if table.IsReserved(next, parent.round, owner) || table.IsReserved(next, nextRound, owner) || table.IsReserved(next, nextRound+1, owner) {
+				continue
+			}
+			count++
+			heap.Push(open, &spaceTimeNode{
+				parent: parent,
+				pos:    next,
+				round:  nextRound,
+				f:      nextRound + uint(next.ManhattanDistance(end)),
+				index:  count,
+			})
+		}
+	}
+	return []pkg.Vector{}, ErrPathReserved
+}
+
+// waitBeforeEntering returns the node from which an agent enters a tile of the
+// given cost, after waiting on its current tile for cost - 1 rounds, or nil if
+// that tile is reserved by another agent meanwhile
+func waitBeforeEntering(current *spaceTimeNode, cost uint, table *ReservationTable, owner string) *spaceTimeNode {
+	for i := uint(1); i < cost; i++ {
+		if table.IsReserved(current.pos, current.round+1, owner) || table.IsReserved(current.pos, current.round+2, owner) {
+			return nil
+		}
+		current = &spaceTimeNode{parent: current, pos: current.pos, round: current.round + 1}
+	}
+	return current
+}
+
+func reconstructSpaceTimePath(current *spaceTimeNode) []pkg.Vector {
+	path := []pkg.Vector{}
+	for current != nil && current.parent != nil {
+		path = append(path, current.pos)
+		current = current.parent
+	}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+	return path
+}
@@ -0,0 +1,216 @@
+package pathfinding
+
+import (
+	"testing"
+
+	"github.com/adrienlucbert/gofeur/board"
+	"github.com/adrienlucbert/gofeur/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+type agent struct {
+	name string
+	pos  pkg.Vector
+	goal pkg.Vector
+	path []pkg.Vector
+}
+
+// isDone returns whether the agent stands next to its goal
+func (a *agent) isDone() bool {
+	return a.path != nil && len(a.path) <= 1
+}
+
+// newCorridorBoard returns a 9x3 board made of a single lane corridor along
+// y = 1, opening on both ends on a 1x3 room, with a pocket at (6, 0). Each
+// room has a blocked tile, used as a goal.
+//
+//	· # # # # # · # ·
+//	· · · · · · · · ·
+//	· # # # # # # # ·
+func newCorridorBoard() board.Board {
+	b := board.New(9, 3)
+	for x := uint(1); x < 8; x++ {
+		if x != 6 {
+			b.At(x, 0).Blocked = true
+		}
+		b.At(x, 2).Blocked = true
+	}
+	return b
+}
+
+func newCorridorAgents() []*agent {
+	return []*agent{
+		{name: "a", pos: pkg.Vector{X: 0, Y: 2}, goal: pkg.Vector{X: 8, Y: 2}},
+		{name: "b", pos: pkg.Vector{X: 8, Y: 0}, goal: pkg.Vector{X: 0, Y: 0}},
+	}
+}
+
+// runNaiveAgents moves agents the way forklifts used to: following a path
+// computed with other agents as obstacles, and computing a new one when the
+// next tile is blocked. It returns whether all agents reached their goal
+// within the given number of rounds.
+func runNaiveAgents(b board.Board, agents []*agent, rounds uint) bool {
+	for round := uint(0); round < rounds; round++ {
+		done := true
+		for _, a := range agents {
+			if a.isDone() {
+				continue
+			}
+			done = false
+			maze := newAgentsBoard(b, agents, a)
+			if a.path == nil || maze.At(uint(a.path[0].X), uint(a.path[0].Y)).Blocked {
+				path, err := Resolve(&maze, a.pos, a.goal)
+				if err != nil {
+					a.path = nil
+					continue
+				}
+				a.path = path
+			}
+			if len(a.path) > 1 {
+				a.pos = a.path[0]
+				a.path = a.path[1:]
+			}
+		}
+		if done {
+			return true
+		}
+	}
+	return false
+}
+
+// newAgentsBoard returns a copy of b in which tiles occupied by agents other
+// than a are blocked, and a's goal is not
+func newAgentsBoard(b board.Board, agents []*agent, a *agent) board.Board {
+	maze := board.New(b.Width(), b.Height())
+	for y := range b {
+		copy(maze[y], b[y])
+	}
+	for _, other := range agents {
+		if other != a {
+			maze.At(uint(other.pos.X), uint(other.pos.Y)).Blocked = true
+		}
+	}
+	maze.At(uint(a.goal.X), uint(a.goal.Y)).Blocked = false
+	return maze
+}
+
+// runCooperativeAgents moves agents following paths resolved with
+// ResolveCooperative. It returns whether all agents reached their goal within
+// the given number of rounds, and fails the test if two agents ever stand on
+// the same tile.
+func runCooperativeAgents(t *testing.T, b board.Board, agents []*agent, rounds uint) bool {
+	t.Helper()
+	table := NewReservationTable()
+	for _, a := range agents {
+		table.Park(a.pos, 0, a.name)
+	}
+	for round := uint(0); round < rounds; round++ {
+		table.Prune(round)
+		done := true
+		for _, a := range agents {
+			if a.isDone() {
+				continue
+			}
+			done = false
+			if a.path == nil {
+				table.Release(a.name)
+				maze := newAgentsBoard(b, nil, a)
+				path, err := ResolveCooperative(&maze, a.pos, a.goal, round, table, a.name)
+				if err != nil {
+					table.Park(a.pos, round, a.name)
+					continue
+				}
+				table.ReservePath(a.pos, path[:len(path)-1], round, a.name)
+				a.path = path
+			}
+			if len(a.path) > 1 {
+				a.pos = a.path[0]
+				a.path = a.path[1:]
+			}
+		}
+		positions := map[pkg.Vector]string{}
+		for _, a := range agents {
+			assert.NotContains(t, positions, a.pos, "agents collided at round %d", round)
+			positions[a.pos] = a.name
+		}
+		if done {
+			return true
+		}
+	}
+	return false
+}
+
+func TestNaiveAgentsDeadlockInCorridor(t *testing.T) {
+	assert.False(t, runNaiveAgents(newCorridorBoard(), newCorridorAgents(), 100))
+}
+
+func TestCooperativeAgentsCrossInCorridor(t *testing.T) {
+	agents := newCorridorAgents()
+	assert.True(t, runCooperativeAgents(t, newCorridorBoard(), agents, 100))
+	assert.Equal(t, pkg.Vector{X: 8, Y: 1}, agents[0].pos)
+	assert.Equal(t, pkg.Vector{X: 0, Y: 1}, agents[1].pos)
+}
+
+func TestResolveCooperativeWaitsForReservedTile(t *testing.T) {
+	b := board.New(3, 1)
+	table := NewReservationTable()
+	table.Reserve(pkg.Vector{X: 1, Y: 0}, 1, "other")
+	path, err := ResolveCooperative(&b, pkg.Vector{X: 0, Y: 0}, pkg.Vector{X: 2, Y: 0}, 0, table, "self")
+	assert.Nil(t, err)
+	assert.Equal(t, []pkg.Vector{{X: 0, Y: 0}, {X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}}, path)
+}
+
+func TestResolveCooperativeAvoidsSwapping(t *testing.T) {
+	b := board.New(3, 2)
+	table := NewReservationTable()
+	table.ReservePath(pkg.Vector{X: 1, Y: 0}, []pkg.Vector{{X: 0, Y: 0}}, 0, "other")
+	path, err := ResolveCooperative(&b, pkg.Vector{X: 0, Y: 1}, pkg.Vector{X: 2, Y: 1}, 0, table, "self")
+	assert.Nil(t, err)
+	assert.Equal(t, []pkg.Vector{{X: 1, Y: 1}, {X: 2, Y: 1}}, path)
+
+	table = NewReservationTable()
+	table.ReservePath(pkg.Vector{X: 1, Y: 0}, []pkg.Vector{{X: 0, Y: 0}, {X: 0, Y: 1}}, 0, "other")
+	_, err = ResolveCooperative(&b, pkg.Vector{X: 0, Y: 0}, pkg.Vector{X: 2, Y: 0}, 0, table, "self")
+	assert.Equal(t, ErrPathReserved, err)
+}
+
+func TestResolveCooperativeNoPath(t *testing.T) {
+	b := board.New(3, 1)
+	b.At(1, 0).Blocked = true
+	_, err := ResolveCooperative(&b, pkg.Vector{X: 0, Y: 0}, pkg.Vector{X: 2, Y: 0}, 0, NewReservationTable(), "self")
+	assert.Equal(t, ErrPathNotFound, err)
+}
+
+func TestReservationTable(t *testing.T) {
+	table := NewReservationTable()
+	table.ReservePath(pkg.Vector{X: 0, Y: 0}, []pkg.Vector{{X: 1, Y: 0}, {X: 2, Y: 0}}, 3, "a")
+
+	assert.True(t, table.IsReserved(pkg.Vector{X: 0, Y: 0}, 3, "b"))
+	assert.False(t, table.IsReserved(pkg.Vector{X: 0, Y: 0}, 3, "a"))
+	assert.False(t, table.IsReserved(pkg.Vector{X: 0, Y: 0}, 4, "b"))
+	assert.True(t, table.IsReserved(pkg.Vector{X: 1, Y: 0}, 4, "b"))
+	assert.False(t, table.IsReserved(pkg.Vector{X: 2, Y: 0}, 4, "b"))
+	assert.True(t, table.IsReserved(pkg.Vector{X: 2, Y: 0}, 5, "b"))
+	assert.True(t, table.IsReserved(pkg.Vector{X: 2, Y: 0}, 1000, "b"))
+
+	table.Prune(5)
+	assert.False(t, table.IsReserved(pkg.Vector{X: 1, Y: 0}, 4, "b"))
+	assert.True(t, table.IsReserved(pkg.Vector{X: 2, Y: 0}, 5, "b"))
+
+	table.Release("a")
+	assert.False(t, table.IsReserved(pkg.Vector{X: 2, Y: 0}, 5, "b"))
+
+	table.Hold(pkg.Vector{X: 1, Y: 0}, 7, "a")
+	assert.True(t, table.IsReserved(pkg.Vector{X: 1, Y: 0}, 7, "b"))
+	assert.True(t, table.IsReserved(pkg.Vector{X: 1, Y: 0}, 8, "b"))
+	assert.False(t, table.IsReserved(pkg.Vector{X: 1, Y: 0}, 9, "b"))
+}
+
+func TestResolveCooperativeWaitsBeforeCostlyTile(t *testing.T) {
+	b := board.New(3, 1)
+	b.At(1, 0).Cost = 3
+	table := NewReservationTable()
+	path, err := ResolveCooperative(&b, pkg.Vector{X: 0, Y: 0}, pkg.Vector{X: 2, Y: 0}, 0, table, "a")
+	assert.Nil(t, err)
+	assert.Equal(t, []pkg.Vector{{X: 0, Y: 0}, {X: 0, Y: 0}, {X: 1, Y: 0}, {X: 2, Y: 0}}, path)
+}
@@ -0,0 +1,69 @@
+package pathfinding
+
+import (
+	"container/heap"
+
+	"github.com/adrienlucbert/gofeur/board"
+	"github.com/adrienlucbert/gofeur/pkg"
+)
+
+// DistanceMap holds the cost of the cheapest paths from a start position to
+// every tile of a board
+type DistanceMap struct {
+	width     int
+	height    int
+	distances []int
+}
+
+// At returns the cost of the cheapest path to the given position, and
+// whether that position is reachable at all
+func (m DistanceMap) At(pos pkg.Vector) (uint, bool) {
+	if pos.X < 0 || pos.Y < 0 || pos.X >= m.width || pos.Y >= m.height {
+		return 0, false
+	}
+	d := m.distances[pos.Y*m.width+pos.X]
+	return uint(d), d >= 0
+}
+
+// Distances computes the cost of the cheapest paths from start to every tile of
+// the board, with 4-connected movement. Blocked tiles are unreachable, except
+// for start itself.
+func Distances(maze *board.Board, start pkg.Vector) DistanceMap {
+	m := DistanceMap{
+		width:     int(maze.Width()),
+		height:    int(maze.Height()),
+		distances: make([]int, maze.Width()*maze.Height()),
+	}
+	for i := range m.distances {
+		m.distances[i] = -1
+	}
+	if start.X < 0 || start.Y < 0 || !maze.IsInBounds(uint(start.X), uint(start.Y)) {
+		return m
+	}
+	startIndex := start.Y*m.width + start.X
+	m.distances[startIndex] = 0
+	open := newOpenSet(len(m.distances))
+	open.update(startIndex, 0, 0, 0)
+	for open.Len() > 0 {
+		current := heap.Pop(open).(int)
+		position := pkg.Vector{X: current % m.width, Y: current / m.width}
+		for _, direction := range straightDirections {
+			next := position.Add(direction)
+			if !isPositionAvailable(maze, next) {
+				continue
+			}
+			tile := maze.At(uint(next.X), uint(next.Y))
+			if !tile.Allows(direction) {
+				continue
+			}
+			nextIndex := next.Y*m.width + next.X
+			distance := m.distances[current] + int(tile.EnterCost())
+			if m.distances[nextIndex] >= 0 && m.distances[nextIndex] <= distance {
+				continue
+			}
+			m.distances[nextIndex] = distance
+			open.update(nextIndex, float32(distance), 0, 0)
+		}
+	}
+	return m
+}
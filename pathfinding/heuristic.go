@@ -0,0 +1,128 @@
+package pathfinding
+
+import (
+	"math"
+
+	"github.com/adrienlucbert/gofeur/board"
+	"github.com/adrienlucbert/gofeur/pkg"
+)
+
+// Heuristic estimates the cost of the path between 2 positions. A* returns the
+// shortest path as long as the heuristic never overestimates that cost. As
+// tiles cost at least 1, the heuristics below assume every tile costs 1.
+type Heuristic func(from pkg.Vector, to pkg.Vector) float32
+
+// diagonalCost is the cost of a diagonal move
+const diagonalCost = math.Sqrt2
+
+// Manhattan is the admissible heuristic for 4-connected movement
+func Manhattan(from pkg.Vector, to pkg.Vector) float32 {
+	return float32(from.ManhattanDistance(to))
+}
+
+// Octile is the admissible heuristic for 8-connected movement, where diagonal
+// moves cost √2
+func Octile(from pkg.Vector, to pkg.Vector) float32 {
+	dx, dy := math.Abs(float64(from.X-to.X)), math.Abs(float64(from.Y-to.Y))
+	return float32(math.Max(dx, dy) + (diagonalCost-1)*math.Min(dx, dy))
+}
+
+// Euclidean is the straight-line distance, admissible for any movement but
+// less informed than Manhattan and Octile
+func Euclidean(from pkg.Vector, to pkg.Vector) float32 {
+	return from.Distance(to)
+}
+
+// Zero turns A* into Dijkstra's algorithm
+func Zero(pkg.Vector, pkg.Vector) float32 {
+	return 0
+}
+
+// Movement represents which neighbour tiles can be reached in one move
+type Movement int
+
+const (
+	// FourConnected allows horizontal and vertical moves
+	FourConnected Movement = iota
+	// EightConnected also allows diagonal moves
+	EightConnected
+)
+
+// CornerCutting represents when a diagonal move is allowed next to blocked
+// tiles, with 8-connected movement
+type CornerCutting int
+
+const (
+	// NoCornerCutting allows diagonal moves only if both tiles they go
+	// between are free
+	NoCornerCutting CornerCutting = iota
+	// CutOneCorner allows diagonal moves if at least one of the tiles they go
+	// between is free
+	CutOneCorner
+	// CutCorners allows diagonal moves even between 2 blocked tiles
+	CutCorners
+)
+
+// Options configures how paths are resolved
+type Options struct {
+	Movement      Movement
+	CornerCutting CornerCutting
+	// Heuristic defaults to the admissible heuristic matching Movement
+	Heuristic Heuristic
+}
+
+var (
+	straightDirections = []pkg.Vector{{X: 0, Y: 1}, {X: 0, Y: -1}, {X: 1, Y: 0}, {X: -1, Y: 0}}
+	diagonalDirections = []pkg.Vector{{X: 1, Y: 1}, {X: 1, Y: -1}, {X: -1, Y: 1}, {X: -1, Y: -1}}
+)
+
+// directions returns the moves allowed by the movement model
+func (o Options) directions() []pkg.Vector {
+	if o.Movement == EightConnected {
+		return append(append([]pkg.Vector{}, straightDirections...), diagonalDirections...)
+	}
+	return straightDirections
+}
+
+func (o Options) heuristic() Heuristic {
+	if o.Heuristic != nil {
+		return o.Heuristic
+	}
+	if o.Movement == EightConnected {
+		return Octile
+	}
+	return Manhattan
+}
+
+// canMove returns whether an agent on a free tile can move in the given
+// direction, and the cost of that move, which is the cost of entering the
+// destination tile, multiplied by √2 for diagonal moves
+func (o Options) canMove(maze *board.Board, from pkg.Vector, direction pkg.Vector) (float32, bool) {
+	to := from.Add(direction)
+	if !isPositionAvailable(maze, to) {
+		return 0, false
+	}
+	tile := maze.At(uint(to.X), uint(to.Y))
+	if !tile.Allows(direction) {
+		return 0, false
+	}
+	cost := float32(tile.EnterCost())
+	if direction.X == 0 || direction.Y == 0 {
+		return cost, true
+	}
+	free := 0
+	if isPositionAvailable(maze, from.Add(pkg.Vector{X: direction.X})) {
+		free++
+	}
+	if isPositionAvailable(maze, from.Add(pkg.Vector{Y: direction.Y})) {
+		free++
+	}
+	switch o.CornerCutting {
+	case NoCornerCutting:
+		return cost * diagonalCost, free == 2
+	case CutOneCorner:
+		return cost * diagonalCost, free >= 1
+	default:
+		return cost * diagonalCost, true
+	}
+}
@@ -2,98 +2,172 @@
 package pathfinding
 
 import (
+	"container/heap"
 	"errors"
+	"math"
 
 	"github.com/adrienlucbert/gofeur/board"
-	"github.com/adrienlucbert/gofeur/optional"
 	"github.com/adrienlucbert/gofeur/pkg"
 )
 
 // ResolveH returns, if possible, a series of moves that form a path between
-// start and end, using a provided heuristic
+// start and end, using a provided heuristic and 4-connected movement
 func ResolveH(maze *board.Board, start pkg.Vector, end pkg.Vector, heuristic func(pkg.Vector) float32) ([]pkg.Vector, error) {
-	return astar(maze, start, end, heuristic)
+	return astar(maze, start, end, Options{Movement: FourConnected}, heuristic)
 }
 
-func distanceBasedHeuristic(current pkg.Vector, end pkg.Vector) float32 {
-	return current.SquaredDistance(end)
-}
-
-// Resolve returns, if possible, a series of moves that form a path between
-// start and end, using a distance-based heuristic
+// Resolve returns, if possible, the shortest series of moves that form a path
+// between start and end, using 4-connected movement and the Manhattan
+// heuristic
 func Resolve(maze *board.Board, start pkg.Vector, end pkg.Vector) ([]pkg.Vector, error) {
-	heuristic := func(n pkg.Vector) float32 {
-		return distanceBasedHeuristic(n, end)
-	}
-	return astar(maze, start, end, heuristic)
+	return ResolveWith(maze, start, end, Options{Movement: FourConnected})
 }
 
-type node struct {
-	parent   *node
-	position pkg.Vector
-	g        float32
-	h        float32
-	f        float32
+// ResolveWith returns, if possible, a series of moves that form a path
+// between start and end, using the given options
+func ResolveWith(maze *board.Board, start pkg.Vector, end pkg.Vector, options Options) ([]pkg.Vector, error) {
+	heuristic := options.heuristic()
+	return astar(maze, start, end, options, func(n pkg.Vector) float32 {
+		return heuristic(n, end)
+	})
 }
 
 // ErrPathNotFound is returned when a path couldn't be found
 var ErrPathNotFound = errors.New("Couldn't find a path")
 
-func astar(b *board.Board, start pkg.Vector, end pkg.Vector, heuristic func(pkg.Vector) float32) ([]pkg.Vector, error) {
-	openQueue := []node{}
-	closedQueue := []node{}
-	openQueue = append(openQueue, node{position: start})
-	for len(openQueue) > 0 {
-		bestNodeIndex, bestNode := findBestNodeInList(openQueue)
+// ErrPathReserved is returned when a path exists, but not one avoiding the
+// tiles reserved by other agents
+var ErrPathReserved = errors.New("Couldn't find a path avoiding other agents")
+
+// openSet is an indexed binary heap of tiles to explore, ordered by f-score.
+// Ties are broken by exploring first the tiles the heuristic deems closest to
+// the end, then those closest to the straight line to the end, then in the
+// order they got their current f-score, which keeps paths deterministic.
+// Each tile is at most once in the heap: when a better path to a tile is
+// found, its key is decreased in place.
+type openSet struct {
+	tiles []int
+	// position holds the index in tiles of each tile of the board, or -1
+	position []int
+	f        []float32
+	h        []float32
+	straight []float32
+	seq      []int
+	count    int
+}
 
-		openQueue = append(openQueue[:bestNodeIndex], openQueue[bestNodeIndex+1:]...)
-		closedQueue = append(closedQueue, bestNode)
+func newOpenSet(size int) *openSet {
+	s := &openSet{
+		position: make([]int, size),
+		f:        make([]float32, size),
+		h:        make([]float32, size),
+		straight: make([]float32, size),
+		seq:      make([]int, size),
+	}
+	for i := range s.position {
+		s.position[i] = -1
+	}
+	return s
+}
 
-		if bestNode.position == end {
-			// Reached the end
-			return reconstructPath(&bestNode), nil
-		}
+func (s *openSet) Len() int { return len(s.tiles) }
 
-		children := []node{}
-		for _, direction := range []pkg.Vector{{X: 0, Y: 1}, {X: 0, Y: -1}, {X: 1, Y: 0}, {X: -1, Y: 0}} {
-			child := node{
-				parent:   &bestNode,
-				position: bestNode.position.Add(direction),
-			}
-			if isPositionAvailable(b, child.position) {
-				children = append(children, child)
-			}
+func (s *openSet) Less(i, j int) bool {
+	a, b := s.tiles[i], s.tiles[j]
+	if s.f[a] != s.f[b] {
+		return s.f[a] < s.f[b]
+	}
+	if s.h[a] != s.h[b] {
+		return s.h[a] < s.h[b]
+	}
+	if s.straight[a] != s.straight[b] {
+		return s.straight[a] < s.straight[b]
+	}
+	return s.seq[a] < s.seq[b]
+}
+
+func (s *openSet) Swap(i, j int) {
+	s.tiles[i], s.tiles[j] = s.tiles[j], s.tiles[i]
+	s.position[s.tiles[i]] = i
+	s.position[s.tiles[j]] = j
+}
+
+func (s *openSet) Push(x any) {
+	tile := x.(int)
+	s.position[tile] = len(s.tiles)
+	s.tiles = append(s.tiles, tile)
+}
+
+func (s *openSet) Pop() any {
+	tile := s.tiles[len(s.tiles)-1]
+	s.tiles = s.tiles[:len(s.tiles)-1]
+	s.position[tile] = -1
+	return tile
+}
+
+// update inserts a tile with the given g-score, heuristic and squared
+// straight-line distance to the end, or decreases its f-score if it's already
+// in the open set
+func (s *openSet) update(tile int, g float32, h float32, straight float32) {
+	s.count++
+	s.f[tile] = g + h
+	s.h[tile] = h
+	s.straight[tile] = straight
+	s.seq[tile] = s.count
+	if s.position[tile] >= 0 {
+		heap.Fix(s, s.position[tile])
+	} else {
+		heap.Push(s, tile)
+	}
+}
+
+// astar returns the shortest path between start and end as long as heuristic
+// never overestimates the distance to end. Tiles are reopened when a shorter
+// path to them is found after they were explored, which only happens if the
+// heuristic is not consistent.
+func astar(b *board.Board, start pkg.Vector, end pkg.Vector, options Options, heuristic func(pkg.Vector) float32) ([]pkg.Vector, error) {
+	if start.X < 0 || start.Y < 0 || !b.IsInBounds(uint(start.X), uint(start.Y)) {
+		return []pkg.Vector{}, ErrPathNotFound
+	}
+	width := int(b.Width())
+	size := width * int(b.Height())
+	gScores := make([]float32, size)
+	parents := make([]int, size)
+	for i := range gScores {
+		gScores[i] = float32(math.Inf(1))
+		parents[i] = -1
+	}
+
+	startIndex := start.Y*width + start.X
+	gScores[startIndex] = 0
+	open := newOpenSet(size)
+	open.update(startIndex, 0, heuristic(start), start.SquaredDistance(end))
+	for open.Len() > 0 {
+		current := heap.Pop(open).(int)
+		position := pkg.Vector{X: current % width, Y: current / width}
+		if position == end {
+			return reconstructPath(parents, current, width), nil
 		}
 
-		for _, child := range children {
-			if findNodeInList(child, closedQueue).HasValue() {
+		for _, direction := range options.directions() {
+			cost, ok := options.canMove(b, position, direction)
+			if !ok {
 				continue
 			}
-			child.g = bestNode.g + 1
-			child.h = heuristic(child.position)
-			child.f = child.g + child.h
-			openNode := findNodeInList(child, openQueue)
-			if openNode.HasValue() && child.g > openNode.Value().g {
+			child := position.Add(direction)
+			childIndex := child.Y*width + child.X
+			g := gScores[current] + cost
+			if g >= gScores[childIndex] {
 				continue
 			}
-			openQueue = append(openQueue, child)
+			gScores[childIndex] = g
+			parents[childIndex] = current
+			open.update(childIndex, g, heuristic(child), child.SquaredDistance(end))
 		}
 	}
 	return []pkg.Vector{}, ErrPathNotFound
 }
 
-func findBestNodeInList(list []node) (int, node) {
-	bestNode := optional.NewEmpty[node]()
-	bestIndex := optional.NewEmpty[int]()
-	for index, node := range list {
-		if !bestNode.HasValue() || node.f < bestNode.Value().f {
-			bestIndex.Set(index)
-			bestNode.Set(list[index])
-		}
-	}
-	return bestIndex.Value(), bestNode.Value()
-}
-
 func isPositionAvailable(maze *board.Board, position pkg.Vector) bool {
 	if position.X < 0 || position.Y < 0 || !maze.IsInBounds(uint(position.X), uint(position.Y)) {
 		return false
@@ -104,21 +178,15 @@ func isPositionAvailable(maze *board.Board, position pkg.Vector) bool {
 	return true
 }
 
-func reconstructPath(current *node) []pkg.Vector {
+// reconstructPath walks the parents of the given tile back to the start, the
+// start itself excluded
+func reconstructPath(parents []int, index int, width int) []pkg.Vector {
 	path := []pkg.Vector{}
-	for current != nil && current.parent != nil {
-		// PERF: reverse list once at the end instead of prepending each node
-		path = append([]pkg.Vector{current.position}, path...)
-		current = current.parent
+	for ; parents[index] >= 0; index = parents[index] {
+		path = append(path, pkg.Vector{X: index % width, Y: index / width})
 	}
-	return path
-}
-
-func findNodeInList(n node, list []node) optional.Optional[node] {
-	for _, it := range list {
-		if it.position == n.position {
-			return optional.New(it)
-		}
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
 	}
-	return optional.NewEmpty[node]()
+	return path
 }
@@ -1,9 +1,13 @@
 package pathfinding
 
 import (
+	"math"
+	"math/rand"
 	"testing"
+	"testing/quick"
 
 	"github.com/adrienlucbert/gofeur/board"
+	"github.com/adrienlucbert/gofeur/optional"
 	"github.com/adrienlucbert/gofeur/pkg"
 	"github.com/stretchr/testify/assert"
 )
@@ -39,3 +43,410 @@ func TestPathfindingNoPath(t *testing.T) {
 	assert.Equal(t, err, ErrPathNotFound)
 	assert.Empty(t, path)
 }
+
+func TestDistances(t *testing.T) {
+	b := board.New(4, 3)
+	b.At(1, 0).Blocked = true
+	b.At(1, 1).Blocked = true
+	b.At(3, 2).Blocked = true
+	b.At(0, 0).Blocked = true
+	distances := Distances(&b, pkg.Vector{X: 0, Y: 0})
+
+	d, ok := distances.At(pkg.Vector{X: 0, Y: 0})
+	assert.True(t, ok)
+	assert.Equal(t, uint(0), d)
+	d, ok = distances.At(pkg.Vector{X: 2, Y: 0})
+	assert.True(t, ok)
+	assert.Equal(t, uint(6), d)
+	_, ok = distances.At(pkg.Vector{X: 1, Y: 1})
+	assert.False(t, ok)
+	_, ok = distances.At(pkg.Vector{X: 3, Y: 2})
+	assert.False(t, ok)
+	_, ok = distances.At(pkg.Vector{X: -1, Y: 0})
+	assert.False(t, ok)
+}
+
+// newRandomBoard returns a board of random size, with random obstacles, and
+// random start and end positions which are never blocked
+func newRandomBoard(seed int64) (board.Board, pkg.Vector, pkg.Vector) {
+	rng := rand.New(rand.NewSource(seed))
+	width, height := 1+rng.Intn(12), 1+rng.Intn(12)
+	b := board.New(uint(width), uint(height))
+	density := rng.Float64() * 0.4
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			b.At(uint(x), uint(y)).Blocked = rng.Float64() < density
+		}
+	}
+	start := pkg.Vector{X: rng.Intn(width), Y: rng.Intn(height)}
+	end := pkg.Vector{X: rng.Intn(width), Y: rng.Intn(height)}
+	b.At(uint(start.X), uint(start.Y)).Blocked = false
+	b.At(uint(end.X), uint(end.Y)).Blocked = false
+	return b, start, end
+}
+
+// isValidPath returns whether path goes from start to end one free adjacent
+// tile at a time
+func isValidPath(b *board.Board, start pkg.Vector, end pkg.Vector, path []pkg.Vector) bool {
+	if len(path) == 0 {
+		return start == end
+	}
+	previous := start
+	for _, pos := range path {
+		if !isPositionAvailable(b, pos) || previous.ManhattanDistance(pos) != 1 {
+			return false
+		}
+		previous = pos
+	}
+	return previous == end
+}
+
+// bfsDistance is the oracle of the property tests: a plain breadth-first
+// search, sharing no code with the pathfinding, which returns the number of
+// moves from start to end on a board of free and blocked tiles, and whether
+// end is reachable at all
+func bfsDistance(b *board.Board, start pkg.Vector, end pkg.Vector) (uint, bool) {
+	width, height := int(b.Width()), int(b.Height())
+	distances := map[pkg.Vector]uint{start: 0}
+	queue := []pkg.Vector{start}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if current == end {
+			return distances[current], true
+		}
+		for _, move := range []pkg.Vector{{X: 0, Y: -1}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: -1, Y: 0}} {
+			next := pkg.Vector{X: current.X + move.X, Y: current.Y + move.Y}
+			if next.X < 0 || next.Y < 0 || next.X >= width || next.Y >= height || b.At(uint(next.X), uint(next.Y)).Blocked {
+				continue
+			}
+			if _, seen := distances[next]; !seen {
+				distances[next] = distances[current] + 1
+				queue = append(queue, next)
+			}
+		}
+	}
+	return 0, false
+}
+
+func TestDistancesMatchesBFS(t *testing.T) {
+	property := func(seed int64) bool {
+		b, start, end := newRandomBoard(seed)
+		expected, expectedReachable := bfsDistance(&b, start, end)
+		distance, reachable := Distances(&b, start).At(end)
+		return reachable == expectedReachable && (!reachable || distance == expected)
+	}
+	assert.Nil(t, quick.Check(property, &quick.Config{MaxCount: 500}))
+}
+
+func TestAstarMatchesBFS(t *testing.T) {
+	heuristics := map[string]func(end pkg.Vector) func(pkg.Vector) float32{
+		"zero": func(pkg.Vector) func(pkg.Vector) float32 {
+			return func(pkg.Vector) float32 { return 0 }
+		},
+		"manhattan": func(end pkg.Vector) func(pkg.Vector) float32 {
+			return func(n pkg.Vector) float32 { return Manhattan(n, end) }
+		},
+	}
+	for name, heuristic := range heuristics {
+		property := func(seed int64) bool {
+			b, start, end := newRandomBoard(seed)
+			expected, reachable := bfsDistance(&b, start, end)
+			path, err := ResolveH(&b, start, end, heuristic(end))
+			if !reachable {
+				return err == ErrPathNotFound && len(path) == 0
+			}
+			return err == nil && uint(len(path)) == expected && isValidPath(&b, start, end, path)
+		}
+		if err := quick.Check(property, &quick.Config{MaxCount: 500}); err != nil {
+			t.Errorf("%s heuristic: %s", name, err)
+		}
+	}
+}
+
+func TestAstarIsDeterministic(t *testing.T) {
+	property := func(seed int64) bool {
+		b, start, end := newRandomBoard(seed)
+		first, firstErr := Resolve(&b, start, end)
+		second, secondErr := Resolve(&b, start, end)
+		return firstErr == secondErr && assert.ObjectsAreEqual(first, second)
+	}
+	assert.Nil(t, quick.Check(property, &quick.Config{MaxCount: 200}))
+}
+
+func TestAstarTieBreaking(t *testing.T) {
+	// on an open board, every monotonic path is a shortest one: ties are
+	// broken towards the straight line to the end, then by the order in
+	// which directions are expanded
+	b := board.New(3, 3)
+	for _, heuristic := range []Heuristic{Zero, Manhattan} {
+		path, err := ResolveWith(&b, pkg.Vector{X: 0, Y: 0}, pkg.Vector{X: 2, Y: 2}, Options{Heuristic: heuristic})
+		assert.Nil(t, err)
+		assert.Equal(t, []pkg.Vector{{X: 0, Y: 1}, {X: 1, Y: 1}, {X: 1, Y: 2}, {X: 2, Y: 2}}, path)
+	}
+}
+
+func TestHeuristics(t *testing.T) {
+	from, to := pkg.Vector{X: 1, Y: 1}, pkg.Vector{X: 4, Y: 5}
+	assert.Equal(t, float32(7), Manhattan(from, to))
+	assert.InDelta(t, 1+3*math.Sqrt2, Octile(from, to), 1e-5)
+	assert.Equal(t, float32(5), Euclidean(from, to))
+	assert.Equal(t, float32(0), Zero(from, to))
+}
+
+func TestResolveEightConnected(t *testing.T) {
+	b := board.New(4, 4)
+	path, err := ResolveWith(&b, pkg.Vector{X: 0, Y: 0}, pkg.Vector{X: 3, Y: 3}, Options{Movement: EightConnected})
+	assert.Nil(t, err)
+	assert.Equal(t, []pkg.Vector{{X: 1, Y: 1}, {X: 2, Y: 2}, {X: 3, Y: 3}}, path)
+}
+
+func TestResolveCornerCutting(t *testing.T) {
+	// going from (0,0) to (1,1) diagonally goes between (1,0) and (0,1)
+	b := board.New(3, 2)
+	b.At(1, 0).Blocked = true
+	start, end := pkg.Vector{X: 0, Y: 0}, pkg.Vector{X: 1, Y: 1}
+
+	path, err := ResolveWith(&b, start, end, Options{Movement: EightConnected, CornerCutting: NoCornerCutting})
+	assert.Nil(t, err)
+	assert.Equal(t, []pkg.Vector{{X: 0, Y: 1}, {X: 1, Y: 1}}, path)
+
+	path, err = ResolveWith(&b, start, end, Options{Movement: EightConnected, CornerCutting: CutOneCorner})
+	assert.Nil(t, err)
+	assert.Equal(t, []pkg.Vector{{X: 1, Y: 1}}, path)
+
+	b.At(0, 1).Blocked = true
+	_, err = ResolveWith(&b, start, end, Options{Movement: EightConnected, CornerCutting: CutOneCorner})
+	assert.Equal(t, ErrPathNotFound, err)
+
+	path, err = ResolveWith(&b, start, end, Options{Movement: EightConnected, CornerCutting: CutCorners})
+	assert.Nil(t, err)
+	assert.Equal(t, []pkg.Vector{{X: 1, Y: 1}}, path)
+}
+
+// pathCost returns the cost of following path from start with 8-connected
+// movement
+func pathCost(start pkg.Vector, path []pkg.Vector) float64 {
+	var cost float64
+	previous := start
+	for _, pos := range path {
+		if pos.X != previous.X && pos.Y != previous.Y {
+			cost += math.Sqrt2
+		} else {
+			cost++
+		}
+		previous = pos
+	}
+	return cost
+}
+
+func TestEightConnectedHeuristicsMatchDijkstra(t *testing.T) {
+	for _, heuristic := range []Heuristic{Octile, Euclidean} {
+		property := func(seed int64) bool {
+			b, start, end := newRandomBoard(seed)
+			options := Options{Movement: EightConnected, CornerCutting: CornerCutting(uint64(seed) % 3)}
+			expected, expectedErr := ResolveWith(&b, start, end, Options{Movement: options.Movement, CornerCutting: options.CornerCutting, Heuristic: Zero})
+			options.Heuristic = heuristic
+			path, err := ResolveWith(&b, start, end, options)
+			if expectedErr != nil {
+				return err == expectedErr
+			}
+			return err == nil && math.Abs(pathCost(start, path)-pathCost(start, expected)) < 1e-4
+		}
+		assert.Nil(t, quick.Check(property, &quick.Config{MaxCount: 300}))
+	}
+}
+
+// sliceAstar is the former A* implementation, which keeps the open and closed
+// sets in slices. It is kept as a baseline for benchmarks.
+type sliceNode struct {
+	parent   *sliceNode
+	position pkg.Vector
+	g        float32
+	h        float32
+	f        float32
+}
+
+func sliceAstar(b *board.Board, start pkg.Vector, end pkg.Vector, heuristic func(pkg.Vector) float32) ([]pkg.Vector, error) {
+	openQueue := []sliceNode{}
+	closedQueue := []sliceNode{}
+	openQueue = append(openQueue, sliceNode{position: start})
+	for len(openQueue) > 0 {
+		bestNodeIndex, bestNode := findBestNodeInList(openQueue)
+
+		openQueue = append(openQueue[:bestNodeIndex], openQueue[bestNodeIndex+1:]...)
+		closedQueue = append(closedQueue, bestNode)
+
+		if bestNode.position == end {
+			// Reached the end
+			return sliceReconstructPath(&bestNode), nil
+		}
+
+		children := []sliceNode{}
+		for _, direction := range []pkg.Vector{{X: 0, Y: 1}, {X: 0, Y: -1}, {X: 1, Y: 0}, {X: -1, Y: 0}} {
+			child := sliceNode{
+				parent:   &bestNode,
+				position: bestNode.position.Add(direction),
+			}
+			if isPositionAvailable(b, child.position) {
+				children = append(children, child)
+			}
+		}
+
+		for _, child := range children {
+			if findNodeInList(child, closedQueue).HasValue() {
+				continue
+			}
+			child.g = bestNode.g + 1
+			child.h = heuristic(child.position)
+			child.f = child.g + child.h
+			openNode := findNodeInList(child, openQueue)
+			if openNode.HasValue() && child.g > openNode.Value().g {
+				continue
+			}
+			openQueue = append(openQueue, child)
+		}
+	}
+	return []pkg.Vector{}, ErrPathNotFound
+}
+
+func findBestNodeInList(list []sliceNode) (int, sliceNode) {
+	bestNode := optional.NewEmpty[sliceNode]()
+	bestIndex := optional.NewEmpty[int]()
+	for index, n := range list {
+		if !bestNode.HasValue() || n.f < bestNode.Value().f {
+			bestIndex.Set(index)
+			bestNode.Set(list[index])
+		}
+	}
+	return bestIndex.Value(), bestNode.Value()
+}
+
+func sliceReconstructPath(current *sliceNode) []pkg.Vector {
+	path := []pkg.Vector{}
+	for current != nil && current.parent != nil {
+		path = append([]pkg.Vector{current.position}, path...)
+		current = current.parent
+	}
+	return path
+}
+
+func findNodeInList(n sliceNode, list []sliceNode) optional.Optional[sliceNode] {
+	for _, it := range list {
+		if it.position == n.position {
+			return optional.New(it)
+		}
+	}
+	return optional.NewEmpty[sliceNode]()
+}
+
+// newBenchmarkBoard returns a size x size board scattered with random
+// pillars, which can't cut the board in parts, and a diagonal wall to go around
+func newBenchmarkBoard(size int) board.Board {
+	rng := rand.New(rand.NewSource(42))
+	b := board.New(uint(size), uint(size))
+	for y := 1; y < size; y += 2 {
+		for x := 1; x < size; x += 2 {
+			b.At(uint(x), uint(y)).Blocked = rng.Intn(2) == 0
+		}
+	}
+	for i := 0; i < size*3/4; i++ {
+		b.At(uint(i), uint(size-1-i)).Blocked = true
+	}
+	b.At(0, 0).Blocked = false
+	b.At(uint(size-1), uint(size-1)).Blocked = false
+	return b
+}
+
+func TestAstarMatchesSliceAstar(t *testing.T) {
+	b := newBenchmarkBoard(30)
+	start, end := pkg.Vector{X: 0, Y: 0}, pkg.Vector{X: 29, Y: 29}
+	heuristic := func(n pkg.Vector) float32 {
+		return n.SquaredDistance(end)
+	}
+	expected, expectedErr := sliceAstar(&b, start, end, heuristic)
+	path, err := ResolveH(&b, start, end, heuristic)
+	assert.Equal(t, expectedErr, err)
+	assert.LessOrEqual(t, len(path), len(expected))
+}
+
+func benchmarkAstar(bench *testing.B, size int, resolve func(*board.Board, pkg.Vector, pkg.Vector, func(pkg.Vector) float32) ([]pkg.Vector, error)) {
+	b := newBenchmarkBoard(size)
+	start, end := pkg.Vector{X: 0, Y: 0}, pkg.Vector{X: size - 1, Y: size - 1}
+	heuristic := func(n pkg.Vector) float32 {
+		return n.SquaredDistance(end)
+	}
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		if _, err := resolve(&b, start, end, heuristic); err != nil {
+			bench.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkAstar50(b *testing.B)       { benchmarkAstar(b, 50, ResolveH) }
+func BenchmarkSliceAstar50(b *testing.B)  { benchmarkAstar(b, 50, sliceAstar) }
+func BenchmarkAstar100(b *testing.B)      { benchmarkAstar(b, 100, ResolveH) }
+func BenchmarkSliceAstar100(b *testing.B) { benchmarkAstar(b, 100, sliceAstar) }
+func BenchmarkAstar500(b *testing.B)      { benchmarkAstar(b, 500, ResolveH) }
+func BenchmarkSliceAstar500(b *testing.B) { benchmarkAstar(b, 500, sliceAstar) }
+
+func benchmarkResolveWith(bench *testing.B, size int, options Options) {
+	b := newBenchmarkBoard(size)
+	start, end := pkg.Vector{X: 0, Y: 0}, pkg.Vector{X: size - 1, Y: size - 1}
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		if _, err := ResolveWith(&b, start, end, options); err != nil {
+			bench.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkResolveManhattan500(b *testing.B) {
+	benchmarkResolveWith(b, 500, Options{Movement: FourConnected})
+}
+
+func BenchmarkResolveOctile500(b *testing.B) {
+	benchmarkResolveWith(b, 500, Options{Movement: EightConnected})
+}
+
+func BenchmarkResolveDijkstra500(b *testing.B) {
+	benchmarkResolveWith(b, 500, Options{Movement: FourConnected, Heuristic: Zero})
+}
+
+func TestResolveWeightedTiles(t *testing.T) {
+	// going straight through (1,0) costs 5, going around costs 4
+	b := board.New(3, 2)
+	b.At(1, 0).Cost = 5
+	path, err := Resolve(&b, pkg.Vector{X: 0, Y: 0}, pkg.Vector{X: 2, Y: 0})
+	assert.Nil(t, err)
+	assert.Equal(t, []pkg.Vector{{X: 0, Y: 1}, {X: 1, Y: 1}, {X: 2, Y: 1}, {X: 2, Y: 0}}, path)
+
+	b.At(1, 0).Cost = 2
+	path, err = Resolve(&b, pkg.Vector{X: 0, Y: 0}, pkg.Vector{X: 2, Y: 0})
+	assert.Nil(t, err)
+	assert.Equal(t, []pkg.Vector{{X: 1, Y: 0}, {X: 2, Y: 0}}, path)
+
+	distances := Distances(&b, pkg.Vector{X: 0, Y: 0})
+	d, ok := distances.At(pkg.Vector{X: 2, Y: 0})
+	assert.True(t, ok)
+	assert.Equal(t, uint(3), d)
+}
+
+func TestResolveOneWayTiles(t *testing.T) {
+	// the top row can only be entered going east
+	b := board.New(3, 2)
+	for x := uint(0); x < 3; x++ {
+		b.At(x, 0).Directions = board.East
+	}
+	path, err := Resolve(&b, pkg.Vector{X: 0, Y: 0}, pkg.Vector{X: 2, Y: 0})
+	assert.Nil(t, err)
+	assert.Equal(t, []pkg.Vector{{X: 1, Y: 0}, {X: 2, Y: 0}}, path)
+
+	path, err = Resolve(&b, pkg.Vector{X: 2, Y: 0}, pkg.Vector{X: 0, Y: 0})
+	assert.Equal(t, ErrPathNotFound, err)
+	assert.Empty(t, path)
+
+	_, ok := Distances(&b, pkg.Vector{X: 2, Y: 1}).At(pkg.Vector{X: 1, Y: 0})
+	assert.False(t, ok)
+}
@@ -7,8 +7,8 @@ import (
 
 // Vector struct stores 2 ints, defining a position or a movement
 type Vector struct {
-	X int
-	Y int
+	X int `json:"x"`
+	Y int `json:"y"`
 }
 
 func (v Vector) String() string {
@@ -32,3 +32,16 @@ func (v Vector) SquaredDistance(rhs Vector) float32 {
 func (v Vector) Distance(rhs Vector) float32 {
 	return float32(math.Sqrt(math.Pow(float64(v.X-rhs.X), 2) + math.Pow(float64(v.Y-rhs.Y), 2)))
 }
+
+// ManhattanDistance calculates the number of horizontal and vertical steps
+// between 2 vectors
+func (v Vector) ManhattanDistance(rhs Vector) int {
+	dx, dy := v.X-rhs.X, v.Y-rhs.Y
+	if dx < 0 {
+		dx = -dx
+	}
+	if dy < 0 {
+		dy = -dy
+	}
+	return dx + dy
+}
@@ -0,0 +1,14 @@
+package pkg
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestManhattanDistance(t *testing.T) {
+	assert.Equal(t, 0, Vector{X: 2, Y: 3}.ManhattanDistance(Vector{X: 2, Y: 3}))
+	assert.Equal(t, 7, Vector{X: 1, Y: 1}.ManhattanDistance(Vector{X: 4, Y: 5}))
+	assert.Equal(t, 7, Vector{X: 4, Y: 5}.ManhattanDistance(Vector{X: 1, Y: 1}))
+	assert.Equal(t, 4, Vector{X: -1, Y: 0}.ManhattanDistance(Vector{X: 1, Y: -2}))
+}
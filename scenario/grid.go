@@ -0,0 +1,208 @@
+package scenario
+
+type cellState int
+
+const (
+	freeCell cellState = iota
+	truckCell
+	obstacleCell
+)
+
+type cell struct {
+	x uint32
+	y uint32
+}
+
+// grid tracks what covers each cell of the warehouse being generated
+type grid struct {
+	width  uint32
+	length uint32
+	states []cellState
+}
+
+func newGrid(width uint32, length uint32) grid {
+	return grid{width: width, length: length, states: make([]cellState, width*length)}
+}
+
+func (g grid) at(c cell) cellState {
+	return g.states[c.y*g.width+c.x]
+}
+
+func (g grid) set(c cell, state cellState) {
+	g.states[c.y*g.width+c.x] = state
+}
+
+// cells returns the cells in the given state, row by row
+func (g grid) cells(state cellState) []cell {
+	cells := []cell{}
+	for y := uint32(0); y < g.length; y++ {
+		for x := uint32(0); x < g.width; x++ {
+			if g.at(cell{x, y}) == state {
+				cells = append(cells, cell{x, y})
+			}
+		}
+	}
+	return cells
+}
+
+func (g grid) count(state cellState) int {
+	return len(g.cells(state))
+}
+
+// sides returns the cells on a side of the warehouse, row by row
+func (g grid) sides() []cell {
+	cells := []cell{}
+	for _, c := range g.cells(freeCell) {
+		if c.x == 0 || c.y == 0 || c.x == g.width-1 || c.y == g.length-1 {
+			cells = append(cells, c)
+		}
+	}
+	return cells
+}
+
+func (g grid) neighbours(c cell) []cell {
+	cells := make([]cell, 0, 4)
+	if c.y > 0 {
+		cells = append(cells, cell{c.x, c.y - 1})
+	}
+	if c.x+1 < g.width {
+		cells = append(cells, cell{c.x + 1, c.y})
+	}
+	if c.y+1 < g.length {
+		cells = append(cells, cell{c.x, c.y + 1})
+	}
+	if c.x > 0 {
+		cells = append(cells, cell{c.x - 1, c.y})
+	}
+	return cells
+}
+
+// ring holds the offsets of the cells around a cell, clockwise from the one
+// above it, so that consecutive cells are neighbours
+var ring = [8][2]int64{{0, -1}, {1, -1}, {1, 0}, {1, 1}, {0, 1}, {-1, 1}, {-1, 0}, {-1, -1}}
+
+// isFree returns whether the cell at the given offset from c is in the
+// warehouse and free
+func (g grid) isFree(c cell, offset [2]int64) bool {
+	x, y := int64(c.x)+offset[0], int64(c.y)+offset[1]
+	return x >= 0 && y >= 0 && x < int64(g.width) && y < int64(g.length) && g.at(cell{uint32(x), uint32(y)}) == freeCell
+}
+
+// arcs returns the number of runs of free cells around c that hold at least
+// one of its free neighbours. Neighbours in the same run are connected to each
+// other without going through c.
+func (g grid) arcs(c cell) int {
+	var free [8]bool
+	for i, offset := range ring {
+		free[i] = g.isFree(c, offset)
+	}
+	arcs := 0
+	for start := range free {
+		if !free[start] || free[(start+7)%8] {
+			continue
+		}
+		for i := start; free[i%8]; i++ {
+			// neighbours are at even indices
+			if i%2 == 0 {
+				arcs++
+				break
+			}
+		}
+	}
+	// no run starts when every cell around c is free
+	if arcs == 0 && free[0] {
+		return 1
+	}
+	return arcs
+}
+
+// canBlock returns whether putting a truck or an obstacle on the given free
+// cell keeps every free cell reachable from any other one, and every truck
+// next to a free cell, provided they were before. Only the cells around it are
+// looked at, unless its free neighbours aren't connected through them.
+func (g grid) canBlock(c cell) bool {
+	free := []cell{}
+	for _, next := range g.neighbours(c) {
+		switch g.at(next) {
+		case freeCell:
+			free = append(free, next)
+		case truckCell:
+			if !g.hasFreeNeighbour(next, c) {
+				return false
+			}
+		}
+	}
+	if len(free) == 0 {
+		return false
+	}
+	return g.arcs(c) <= 1 || g.connects(free, c)
+}
+
+// hasFreeNeighbour returns whether c is next to a free cell other than except
+func (g grid) hasFreeNeighbour(c cell, except cell) bool {
+	for _, next := range g.neighbours(c) {
+		if next != except && g.at(next) == freeCell {
+			return true
+		}
+	}
+	return false
+}
+
+// connects returns whether the given free cells can be got to from each other
+// without going through the cell in the way. A search runs from each of them
+// in turn, one cell at a time, until they all met, or until one of them is
+// over, which is quick when a small pocket would be cut off.
+func (g grid) connects(cells []cell, inTheWay cell) bool {
+	// group holds, for each search, the search it merged into, if any
+	group := make([]int, len(cells))
+	queues := make([][]cell, len(cells))
+	owners := map[cell]int{inTheWay: -1}
+	for i, c := range cells {
+		group[i] = i
+		queues[i] = []cell{c}
+		owners[c] = i
+	}
+	root := func(i int) int {
+		for group[i] != i {
+			i = group[i]
+		}
+		return i
+	}
+	groups := len(cells)
+	for groups > 1 {
+		for i := range queues {
+			if len(queues[i]) == 0 {
+				continue
+			}
+			current := queues[i][0]
+			queues[i] = queues[i][1:]
+			for _, next := range g.neighbours(current) {
+				if g.at(next) != freeCell {
+					continue
+				}
+				owner, seen := owners[next]
+				if !seen {
+					owners[next] = i
+					queues[i] = append(queues[i], next)
+				} else if owner >= 0 && root(owner) != root(i) {
+					group[root(owner)] = root(i)
+					groups--
+				}
+			}
+			if g.isOver(root(i), queues, root) && groups > 1 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// isOver returns whether every search of the given group ran out of cells
+func (g grid) isOver(group int, queues [][]cell, root func(int) int) bool {
+	for i := range queues {
+		if root(i) == group && len(queues[i]) > 0 {
+			return false
+		}
+	}
+	return true
+}
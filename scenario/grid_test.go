@@ -0,0 +1,64 @@
+package scenario
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// isConnected returns whether every free cell can be got to from any other
+// one, and whether every truck is next to a free cell, by looking at the whole
+// grid
+func (g grid) isConnected() bool {
+	free := g.cells(freeCell)
+	if len(free) == 0 {
+		return false
+	}
+
+	seen := make([]bool, len(g.states))
+	seen[free[0].y*g.width+free[0].x] = true
+	queue := []cell{free[0]}
+	for i := 0; i < len(queue); i++ {
+		for _, next := range g.neighbours(queue[i]) {
+			if index := next.y*g.width + next.x; !seen[index] && g.at(next) == freeCell {
+				seen[index] = true
+				queue = append(queue, next)
+			}
+		}
+	}
+	if len(queue) != len(free) {
+		return false
+	}
+
+	for _, truck := range g.cells(truckCell) {
+		if !g.hasFreeNeighbour(truck, truck) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCanBlockMatchesIsConnected(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 50; i++ {
+		g := newGrid(uint32(rng.Intn(12)+1), uint32(rng.Intn(12)+1))
+		cells := g.cells(freeCell)
+		rng.Shuffle(len(cells), func(i, j int) { cells[i], cells[j] = cells[j], cells[i] })
+		for _, c := range cells {
+			state := obstacleCell
+			if rng.Intn(4) == 0 {
+				state = truckCell
+			}
+			g.set(c, state)
+			expected := g.isConnected()
+			g.set(c, freeCell)
+			if !assert.Equal(t, expected, g.canBlock(c), "grid %d, cell %v", i, c) {
+				return
+			}
+			if expected {
+				g.set(c, state)
+			}
+		}
+	}
+}
@@ -0,0 +1,345 @@
+// Package scenario generates random, valid and solvable simulation scenarios
+package scenario
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/adrienlucbert/gofeur/parsing"
+)
+
+// Params configures the generation of a scenario. The same parameters always
+// give the same scenario.
+type Params struct {
+	Seed   int64
+	Width  uint32
+	Length uint32
+	Cycles uint32
+
+	Parcels uint32
+	// Colors gives the relative frequency of each parcel class of the default
+	// catalogue, nil meaning they are all as frequent
+	Colors Shares
+
+	Forklifts uint32
+
+	Trucks uint32
+	// Capacity is the range of the trucks' maximum weight, and Delay the
+	// range of the number of rounds they are away once gone
+	Capacity Range
+	Delay    Range
+
+	// ObstacleDensity is the share, from 0 to 1, of the cells left free by
+	// entities that are covered by obstacles
+	ObstacleDensity float64
+}
+
+// DefaultParams returns the parameters of a small scenario
+func DefaultParams() Params {
+	return Params{
+		Seed:            1,
+		Width:           10,
+		Length:          10,
+		Cycles:          1000,
+		Parcels:         10,
+		Forklifts:       2,
+		Trucks:          2,
+		Capacity:        Range{Min: 500, Max: 1000},
+		Delay:           Range{Min: 1, Max: 5},
+		ObstacleDensity: 0.1,
+	}
+}
+
+var (
+	errNoForklift      = errors.New("a scenario needs at least one forklift")
+	errNoTruck         = errors.New("a scenario needs at least one truck")
+	errInvalidDensity  = errors.New("the obstacle density should be between 0 and 1")
+	errInvalidRange    = errors.New("invalid range, expected min-max or a single value")
+	errInvalidShares   = errors.New("invalid shares, expected name=share pairs separated by commas")
+	errNoColor         = errors.New("at least one parcel color should have a share")
+	errTooManyEntities = errors.New("the warehouse is too small for that many entities")
+	errTooManyCells    = errors.New("the warehouse should have at most 4294967295 cells")
+)
+
+type emptyRangeError struct {
+	r Range
+}
+
+func (err emptyRangeError) Error() string {
+	return fmt.Sprintf("the range %s is empty", err.r)
+}
+
+type unknownColorError struct {
+	name string
+}
+
+func (err unknownColorError) Error() string {
+	return fmt.Sprintf("unknown parcel color '%s'", err.name)
+}
+
+type tooLightTrucksError struct {
+	capacity Range
+	weight   uint32
+}
+
+func (err tooLightTrucksError) Error() string {
+	return fmt.Sprintf("trucks can carry at most %d, which is lighter than the heaviest parcels (%d)", err.capacity.Max, err.weight)
+}
+
+type unsolvableScenarioError struct {
+	diagnostic parsing.Diagnostic
+}
+
+func (err unsolvableScenarioError) Error() string {
+	return fmt.Sprintf("generated an unsolvable scenario: %s [%s]", err.diagnostic.Message, err.diagnostic.Code)
+}
+
+// Generate returns a random scenario. Trucks are on the sides of the
+// warehouse, and neither trucks nor obstacles split the free cells, so that
+// the scenario is valid, as told by parsing.VerifySimulationValidity, and that
+// every parcel can be brought to a truck, as told by parsing.Lint.
+func Generate(params Params) (parsing.Simulation, error) {
+	classes, err := params.classes()
+	if err != nil {
+		return parsing.Simulation{}, err
+	}
+	if err := params.check(classes); err != nil {
+		return parsing.Simulation{}, err
+	}
+
+	rng := rand.New(rand.NewSource(params.Seed))
+	g := newGrid(params.Width, params.Length)
+	simul := parsing.NewSimulation(params.Width, params.Length, params.Cycles)
+	warehouse := &simul.Warehouse
+
+	sides := g.sides()
+	rng.Shuffle(len(sides), func(i, j int) { sides[i], sides[j] = sides[j], sides[i] })
+	heaviest := heaviestClass(classes)
+	for _, cell := range sides {
+		if uint32(len(warehouse.Trucks)) == params.Trucks {
+			break
+		}
+		if !g.canBlock(cell) {
+			continue
+		}
+		g.set(cell, truckCell)
+		capacity := params.Capacity.pick(rng)
+		// the first truck can carry every parcel
+		if len(warehouse.Trucks) == 0 && params.Parcels > 0 && capacity < heaviest {
+			capacity = Range{Min: heaviest, Max: params.Capacity.Max}.pick(rng)
+		}
+		name := fmt.Sprintf("truck_%d", len(warehouse.Trucks)+1)
+		warehouse.Trucks = append(warehouse.Trucks, parsing.NewTruck(name, cell.x, cell.y, capacity, params.Delay.pick(rng)))
+	}
+	if uint32(len(warehouse.Trucks)) < params.Trucks {
+		return parsing.Simulation{}, errTooManyEntities
+	}
+
+	free := g.count(freeCell)
+	needed := int(params.Forklifts + params.Parcels)
+	if free < needed {
+		return parsing.Simulation{}, errTooManyEntities
+	}
+
+	cells := g.cells(freeCell)
+	rng.Shuffle(len(cells), func(i, j int) { cells[i], cells[j] = cells[j], cells[i] })
+	obstacles := int(params.ObstacleDensity * float64(free-needed))
+	for _, cell := range cells {
+		if obstacles == 0 {
+			break
+		}
+		if !g.canBlock(cell) {
+			continue
+		}
+		g.set(cell, obstacleCell)
+		warehouse.Obstacles = append(warehouse.Obstacles, parsing.NewObstacle(cell.x, cell.y, 1, 1))
+		obstacles--
+	}
+
+	cells = g.cells(freeCell)
+	rng.Shuffle(len(cells), func(i, j int) { cells[i], cells[j] = cells[j], cells[i] })
+	for i, cell := range cells[:params.Forklifts] {
+		warehouse.Forklifts = append(warehouse.Forklifts, parsing.NewForklift(fmt.Sprintf("forklift_%d", i+1), cell.x, cell.y))
+	}
+	for i, cell := range cells[params.Forklifts:needed] {
+		warehouse.Parcels = append(warehouse.Parcels, parsing.NewParcel(fmt.Sprintf("parcel_%d", i+1), cell.x, cell.y, classes.pick(rng)))
+	}
+
+	if err := parsing.VerifySimulationValidity(simul); err != nil {
+		return parsing.Simulation{}, err
+	}
+	if diagnostics := parsing.Lint(simul); len(diagnostics) > 0 {
+		return parsing.Simulation{}, unsolvableScenarioError{diagnostic: diagnostics[0]}
+	}
+	return simul, nil
+}
+
+// check ensures the parameters can give a scenario
+func (params Params) check(classes weightedClasses) error {
+	if params.Forklifts == 0 {
+		return errNoForklift
+	}
+	if params.Trucks == 0 {
+		return errNoTruck
+	}
+	if math.IsNaN(params.ObstacleDensity) || params.ObstacleDensity < 0 || params.ObstacleDensity > 1 {
+		return errInvalidDensity
+	}
+	// cells are indexed with 32 bits integers
+	if uint64(params.Width)*uint64(params.Length) > math.MaxUint32 {
+		return errTooManyCells
+	}
+	for _, r := range []Range{params.Capacity, params.Delay} {
+		if r.Min > r.Max {
+			return emptyRangeError{r: r}
+		}
+	}
+	if heaviest := heaviestClass(classes); params.Parcels > 0 && params.Capacity.Max < heaviest {
+		return tooLightTrucksError{capacity: params.Capacity, weight: heaviest}
+	}
+	return nil
+}
+
+// weightedClass is a parcel class, along with how often it is picked
+type weightedClass struct {
+	class parsing.ParcelClass
+	share uint
+}
+
+type weightedClasses []weightedClass
+
+// classes returns the parcel classes that may be picked, in the order of the
+// default catalogue
+func (params Params) classes() (weightedClasses, error) {
+	classes := weightedClasses{}
+	names := map[string]bool{}
+	for _, class := range parsing.DefaultParcelClasses() {
+		names[class.Name] = true
+		share := uint(1)
+		if params.Colors != nil {
+			share = params.Colors[class.Name]
+		}
+		if share > 0 {
+			classes = append(classes, weightedClass{class: class, share: share})
+		}
+	}
+	for _, name := range params.Colors.names() {
+		if !names[name] {
+			return nil, unknownColorError{name: name}
+		}
+	}
+	if len(classes) == 0 {
+		return nil, errNoColor
+	}
+	return classes, nil
+}
+
+func (classes weightedClasses) pick(rng *rand.Rand) parsing.ParcelClass {
+	total := uint(0)
+	for _, c := range classes {
+		total += c.share
+	}
+	n := uint(rng.Int63n(int64(total)))
+	for _, c := range classes {
+		if n < c.share {
+			return c.class
+		}
+		n -= c.share
+	}
+	panic("Unreachable")
+}
+
+func heaviestClass(classes weightedClasses) uint32 {
+	heaviest := uint32(0)
+	for _, c := range classes {
+		if weight := uint32(c.class.Weight); weight > heaviest {
+			heaviest = weight
+		}
+	}
+	return heaviest
+}
+
+// Range is an inclusive range of unsigned integers. It is written min-max, or
+// as a single value when Min and Max are equal.
+type Range struct {
+	Min uint32
+	Max uint32
+}
+
+func (r Range) String() string {
+	if r.Min == r.Max {
+		return strconv.FormatUint(uint64(r.Min), 10)
+	}
+	return fmt.Sprintf("%d-%d", r.Min, r.Max)
+}
+
+// Set parses a range, so that ranges can be command-line flags
+func (r *Range) Set(value string) error {
+	min, max, found := strings.Cut(value, "-")
+	if !found {
+		max = min
+	}
+	parsedMin, err := strconv.ParseUint(min, 10, 32)
+	if err != nil {
+		return errInvalidRange
+	}
+	parsedMax, err := strconv.ParseUint(max, 10, 32)
+	if err != nil {
+		return errInvalidRange
+	}
+	r.Min, r.Max = uint32(parsedMin), uint32(parsedMax)
+	if r.Min > r.Max {
+		return emptyRangeError{r: *r}
+	}
+	return nil
+}
+
+func (r Range) pick(rng *rand.Rand) uint32 {
+	return r.Min + uint32(rng.Int63n(int64(r.Max-r.Min)+1))
+}
+
+// Shares gives the relative frequency of named things. It is written as
+// name=share pairs separated by commas, such as yellow=2,blue=1.
+type Shares map[string]uint
+
+func (s Shares) String() string {
+	pairs := make([]string, 0, len(s))
+	for _, name := range s.names() {
+		pairs = append(pairs, fmt.Sprintf("%s=%d", name, s[name]))
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set parses shares, so that shares can be command-line flags. Names are
+// lowercased.
+func (s *Shares) Set(value string) error {
+	shares := Shares{}
+	for _, pair := range strings.Split(value, ",") {
+		name, share, found := strings.Cut(pair, "=")
+		if !found || name == "" {
+			return errInvalidShares
+		}
+		parsed, err := strconv.ParseUint(share, 10, 32)
+		if err != nil {
+			return errInvalidShares
+		}
+		shares[strings.ToLower(name)] = uint(parsed)
+	}
+	*s = shares
+	return nil
+}
+
+// names returns the names of the shares, sorted
+func (s Shares) names() []string {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
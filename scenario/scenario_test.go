@@ -0,0 +1,135 @@
+package scenario
+
+import (
+	"math"
+	"testing"
+
+	"github.com/adrienlucbert/gofeur/parsing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	paramsList := []Params{DefaultParams()}
+
+	crowded := DefaultParams()
+	crowded.Width, crowded.Length = 6, 4
+	crowded.Parcels, crowded.Forklifts, crowded.Trucks = 8, 3, 6
+	crowded.ObstacleDensity = 1
+	paramsList = append(paramsList, crowded)
+
+	corridor := DefaultParams()
+	corridor.Width, corridor.Length = 1, 12
+	corridor.Parcels, corridor.Forklifts, corridor.Trucks = 3, 1, 2
+	corridor.ObstacleDensity = 0.5
+	paramsList = append(paramsList, corridor)
+
+	blue := DefaultParams()
+	blue.Width, blue.Length = 30, 20
+	blue.Parcels = 50
+	blue.Colors = Shares{"blue": 1}
+	blue.Capacity = Range{Min: 100, Max: 500}
+	blue.ObstacleDensity = 0.4
+	paramsList = append(paramsList, blue)
+
+	for i, params := range paramsList {
+		for seed := int64(0); seed < 20; seed++ {
+			params.Seed = seed
+			simul, err := Generate(params)
+			if !assert.Nil(t, err, "params %d, seed %d", i, seed) {
+				continue
+			}
+			warehouse := simul.Warehouse
+			assert.Len(t, warehouse.Parcels, int(params.Parcels), "params %d, seed %d", i, seed)
+			assert.Len(t, warehouse.Forklifts, int(params.Forklifts), "params %d, seed %d", i, seed)
+			assert.Len(t, warehouse.Trucks, int(params.Trucks), "params %d, seed %d", i, seed)
+			assert.Nil(t, parsing.VerifySimulationValidity(simul), "params %d, seed %d", i, seed)
+			assert.Empty(t, parsing.Lint(simul), "params %d, seed %d", i, seed)
+			for _, parcel := range warehouse.Parcels {
+				if params.Colors != nil {
+					assert.Equal(t, "blue", parcel.Color)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateIsDeterministic(t *testing.T) {
+	params := DefaultParams()
+	first, err := Generate(params)
+	assert.Nil(t, err)
+	second, err := Generate(params)
+	assert.Nil(t, err)
+	assert.Equal(t, first, second)
+
+	params.Seed++
+	third, err := Generate(params)
+	assert.Nil(t, err)
+	assert.NotEqual(t, first, third)
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tooManyParcels := DefaultParams()
+	tooManyParcels.Parcels = 100
+
+	tooManyTrucks := DefaultParams()
+	tooManyTrucks.Width, tooManyTrucks.Length, tooManyTrucks.Trucks = 3, 3, 8
+
+	noForklift := DefaultParams()
+	noForklift.Forklifts = 0
+
+	noTruck := DefaultParams()
+	noTruck.Trucks = 0
+
+	unknownColor := DefaultParams()
+	unknownColor.Colors = Shares{"red": 1}
+
+	noColor := DefaultParams()
+	noColor.Colors = Shares{"yellow": 0}
+
+	tooLightTrucks := DefaultParams()
+	tooLightTrucks.Capacity = Range{Min: 100, Max: 200}
+
+	invalidDensity := DefaultParams()
+	invalidDensity.ObstacleDensity = 1.5
+
+	nanDensity := DefaultParams()
+	nanDensity.ObstacleDensity = math.NaN()
+
+	tooManyCells := DefaultParams()
+	tooManyCells.Width, tooManyCells.Length = 65536, 65536
+
+	for _, params := range []Params{tooManyParcels, tooManyTrucks, noForklift, noTruck, unknownColor, noColor, tooLightTrucks, invalidDensity, nanDensity, tooManyCells} {
+		_, err := Generate(params)
+		assert.NotNil(t, err)
+	}
+
+	lightParcels := tooLightTrucks
+	lightParcels.Colors = Shares{"yellow": 3, "green": 1}
+	_, err := Generate(lightParcels)
+	assert.Nil(t, err)
+}
+
+func TestRangeSet(t *testing.T) {
+	var r Range
+	assert.Nil(t, r.Set("500-1000"))
+	assert.Equal(t, Range{Min: 500, Max: 1000}, r)
+	assert.Equal(t, "500-1000", r.String())
+	assert.Nil(t, r.Set("3"))
+	assert.Equal(t, Range{Min: 3, Max: 3}, r)
+	assert.Equal(t, "3", r.String())
+
+	for _, value := range []string{"", "a-3", "3-", "-3", "5-3", "1-2-3"} {
+		assert.NotNil(t, r.Set(value), value)
+	}
+}
+
+func TestSharesSet(t *testing.T) {
+	var s Shares
+	assert.Nil(t, s.Set("Yellow=2,blue=1"))
+	assert.Equal(t, Shares{"yellow": 2, "blue": 1}, s)
+	assert.Equal(t, "blue=1,yellow=2", s.String())
+
+	for _, value := range []string{"", "yellow", "=2", "yellow=a", "yellow=2,"} {
+		assert.NotNil(t, s.Set(value), value)
+	}
+}
@@ -0,0 +1,216 @@
+package simulation
+
+import (
+	"math"
+
+	"github.com/adrienlucbert/gofeur/pathfinding"
+	"github.com/adrienlucbert/gofeur/pkg"
+)
+
+func init() {
+	RegisterStrategy("assignment", func() Strategy { return &AssignmentStrategy{} })
+}
+
+// AssignmentStrategy assigns parcels to empty forklifts so that the total
+// distance they travel is minimal, using the actual path lengths on the
+// board. The assignment is recomputed every round, so forklifts can swap
+// targets when parcels become free. Trucks are handled as in GreedyStrategy.
+type AssignmentStrategy struct {
+	GreedyStrategy
+	plan map[*Forklift]*Parcel
+}
+
+// SelectParcel implements Strategy.SelectParcel(). Forklifts that became
+// empty since the plan was made are assigned the parcels left over.
+func (a *AssignmentStrategy) SelectParcel(simulation *Simulation, forklift *Forklift) *Parcel {
+	if a.plan == nil {
+		a.Plan(simulation)
+	}
+	if _, planned := a.plan[forklift]; !planned {
+		a.planFreed(simulation)
+	}
+	return a.plan[forklift]
+}
+
+// KeepTarget implements Rebalancer.KeepTarget()
+func (a *AssignmentStrategy) KeepTarget(simulation *Simulation, forklift *Forklift, parcel *Parcel) bool {
+	return a.SelectParcel(simulation, forklift) == parcel
+}
+
+// unreachableCost is the cost of assigning a parcel a forklift can't reach. It
+// is large enough to never be preferred to a reachable parcel, and small
+// enough not to overflow when summed.
+const unreachableCost = math.MaxInt32
+
+// Plan implements Planner.Plan()
+func (a *AssignmentStrategy) Plan(simulation *Simulation) {
+	a.plan = map[*Forklift]*Parcel{}
+
+	forklifts := []*Forklift{}
+	parcels := []*Parcel{}
+	for i := range simulation.forklifts {
+		forklift := &simulation.forklifts[i]
+		if forklift.status != Empty {
+			continue
+		}
+		forklifts = append(forklifts, forklift)
+		if forklift.target.HasValue() {
+			parcels = append(parcels, forklift.target.Value().(*Parcel))
+		}
+	}
+	for i := range simulation.parcels {
+		if simulation.parcels[i].IsAvailable() {
+			parcels = append(parcels, &simulation.parcels[i])
+		}
+	}
+	a.assign(simulation, forklifts, parcels)
+}
+
+// planFreed assigns the parcels no forklift is heading to, nor planned to, to
+// the empty forklifts left out of the plan, such as the ones which dropped
+// their parcel during the round
+func (a *AssignmentStrategy) planFreed(simulation *Simulation) {
+	planned := map[*Parcel]bool{}
+	for _, parcel := range a.plan {
+		planned[parcel] = true
+	}
+	forklifts := []*Forklift{}
+	for i := range simulation.forklifts {
+		forklift := &simulation.forklifts[i]
+		if _, ok := a.plan[forklift]; !ok && forklift.status == Empty && !forklift.target.HasValue() {
+			forklifts = append(forklifts, forklift)
+		}
+	}
+	parcels := []*Parcel{}
+	for i := range simulation.parcels {
+		if parcel := &simulation.parcels[i]; parcel.IsAvailable() && !planned[parcel] {
+			parcels = append(parcels, parcel)
+		}
+	}
+	a.assign(simulation, forklifts, parcels)
+}
+
+// assign adds to the plan the assignment of parcels to forklifts which
+// minimizes the distance they travel. Every forklift is part of the plan
+// afterwards, without parcel if none is left or reachable.
+func (a *AssignmentStrategy) assign(simulation *Simulation, forklifts []*Forklift, parcels []*Parcel) {
+	for _, forklift := range forklifts {
+		a.plan[forklift] = nil
+	}
+	if len(forklifts) == 0 || len(parcels) == 0 {
+		return
+	}
+
+	cost := make([][]int, len(forklifts))
+	for i, forklift := range forklifts {
+		distances := pathfinding.Distances(&simulation.board, forklift.pos)
+		cost[i] = make([]int, len(parcels))
+		for j, parcel := range parcels {
+			cost[i][j] = distanceToProp(distances, parcel.pos)
+		}
+	}
+	for i, j := range minCostAssignment(cost) {
+		if j >= 0 && cost[i][j] < unreachableCost {
+			a.plan[forklifts[i]] = parcels[j]
+		}
+	}
+}
+
+// distanceToProp returns the distance to the closest tile next to pos
+func distanceToProp(distances pathfinding.DistanceMap, pos pkg.Vector) int {
+	best := unreachableCost
+	for _, direction := range []pkg.Vector{{X: 0, Y: 1}, {X: 0, Y: -1}, {X: 1, Y: 0}, {X: -1, Y: 0}} {
+		if d, ok := distances.At(pos.Add(direction)); ok && int(d) < best {
+			best = int(d)
+		}
+	}
+	return best
+}
+
+// minCostAssignment solves the assignment problem described by the given cost
+// matrix using the Hungarian algorithm. It returns, for each row, the column
+// it is assigned to, or -1 if there are less columns than rows and the row
+// was left out.
+func minCostAssignment(cost [][]int) []int {
+	if len(cost) == 0 || len(cost[0]) == 0 {
+		assignment := make([]int, len(cost))
+		for i := range assignment {
+			assignment[i] = -1
+		}
+		return assignment
+	}
+	if len(cost) > len(cost[0]) {
+		transposed := make([][]int, len(cost[0]))
+		for j := range transposed {
+			transposed[j] = make([]int, len(cost))
+			for i := range cost {
+				transposed[j][i] = cost[i][j]
+			}
+		}
+		assignment := make([]int, len(cost))
+		for i := range assignment {
+			assignment[i] = -1
+		}
+		for j, i := range minCostAssignment(transposed) {
+			assignment[i] = j
+		}
+		return assignment
+	}
+
+	// Rows and columns are 1-indexed below, 0 being a sentinel
+	n, m := len(cost), len(cost[0])
+	u := make([]int, n+1)
+	v := make([]int, m+1)
+	rowOf := make([]int, m+1)
+	way := make([]int, m+1)
+	for i := 1; i <= n; i++ {
+		rowOf[0] = i
+		col := 0
+		minv := make([]int, m+1)
+		used := make([]bool, m+1)
+		for j := range minv {
+			minv[j] = math.MaxInt
+		}
+		for rowOf[col] != 0 {
+			used[col] = true
+			row := rowOf[col]
+			delta := math.MaxInt
+			next := 0
+			for j := 1; j <= m; j++ {
+				if used[j] {
+					continue
+				}
+				if reduced := cost[row-1][j-1] - u[row] - v[j]; reduced < minv[j] {
+					minv[j] = reduced
+					way[j] = col
+				}
+				if minv[j] < delta {
+					delta = minv[j]
+					next = j
+				}
+			}
+			for j := 0; j <= m; j++ {
+				if used[j] {
+					u[rowOf[j]] += delta
+					v[j] -= delta
+				} else {
+					minv[j] -= delta
+				}
+			}
+			col = next
+		}
+		for col != 0 {
+			prev := way[col]
+			rowOf[col] = rowOf[prev]
+			col = prev
+		}
+	}
+
+	assignment := make([]int, n)
+	for j := 1; j <= m; j++ {
+		if rowOf[j] != 0 {
+			assignment[rowOf[j]-1] = j - 1
+		}
+	}
+	return assignment
+}
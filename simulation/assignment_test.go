@@ -0,0 +1,91 @@
+package simulation
+
+import (
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/adrienlucbert/gofeur/config"
+	"github.com/adrienlucbert/gofeur/parsing"
+	"github.com/stretchr/testify/assert"
+)
+
+func bruteForceAssignmentCost(cost [][]int, row int, used []bool) int {
+	if row == len(cost) {
+		return 0
+	}
+	best := -1
+	for j := range cost[row] {
+		if used[j] {
+			continue
+		}
+		used[j] = true
+		if c := cost[row][j] + bruteForceAssignmentCost(cost, row+1, used); best < 0 || c < best {
+			best = c
+		}
+		used[j] = false
+	}
+	return best
+}
+
+func TestMinCostAssignment(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+	for n := 1; n <= 5; n++ {
+		for m := n; m <= 6; m++ {
+			cost := make([][]int, n)
+			for i := range cost {
+				cost[i] = make([]int, m)
+				for j := range cost[i] {
+					cost[i][j] = rng.Intn(20)
+				}
+			}
+			assignment := minCostAssignment(cost)
+			total := 0
+			seen := map[int]bool{}
+			for i, j := range assignment {
+				assert.False(t, seen[j])
+				seen[j] = true
+				total += cost[i][j]
+			}
+			assert.Equal(t, bruteForceAssignmentCost(cost, 0, make([]bool, m)), total)
+		}
+	}
+}
+
+func TestMinCostAssignmentMoreRowsThanColumns(t *testing.T) {
+	cost := [][]int{{5, 1}, {1, 5}, {9, 9}}
+	assert.Equal(t, []int{1, 0, -1}, minCostAssignment(cost))
+}
+
+func TestAssignmentStrategy(t *testing.T) {
+	strategy, err := NewStrategy("assignment")
+	assert.Nil(t, err)
+	sim := runSimulationWith(t, "testdata/basic.txt", strategy)
+	report := sim.Report()
+	assert.Equal(t, "finished", report.Status)
+	assert.Equal(t, uint(3), report.ParcelsDelivered)
+}
+
+func TestAssignmentStrategyPlansFreedForklifts(t *testing.T) {
+	config.Set("logLevel", "None")
+	gofeur, err := parsing.ParseReader(strings.NewReader("5 5 100\nparcel_a 1 1 yellow\nparcel_b 3 2 yellow\nforklift 1 2\ntruck 0 2 500 3"), parsing.TextFormat)
+	assert.Nil(t, err)
+	strategy, err := NewStrategy("assignment")
+	assert.Nil(t, err)
+	sim := New(&gofeur, strategy)
+	r := &recorder{}
+	sim.Subscribe(r)
+
+	actions := []string{}
+	for len(actions) < 3 {
+		sim.Step()
+		for _, event := range r.events {
+			if event.Entity == "forklift" {
+				actions = append(actions, event.Action())
+			}
+		}
+		r.events = nil
+	}
+	// the forklift heads to the second parcel as soon as it dropped the first
+	assert.Equal(t, []string{"TAKE parcel_a YELLOW", "LEAVE parcel_a YELLOW", "GO [2,2]"}, actions)
+}
@@ -0,0 +1,258 @@
+package simulation
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"github.com/adrienlucbert/gofeur/logger"
+	"github.com/adrienlucbert/gofeur/pkg"
+)
+
+// DeadlockRecovery represents what the simulation does when it detects that
+// forklifts are stuck
+type DeadlockRecovery int
+
+const (
+	// StopOnDeadlock ends the simulation with the Deadlocked status
+	StopOnDeadlock DeadlockRecovery = iota
+	// YieldOnDeadlock makes stuck forklifts drop their target and step aside,
+	// then ends the simulation if they are still stuck after a few attempts
+	YieldOnDeadlock
+)
+
+// SuggestedDeadlockRounds is a number of rounds without progress after which a
+// repeating state can safely be considered a deadlock. Detection is disabled
+// unless Simulation.DeadlockRounds is set.
+const SuggestedDeadlockRounds = 20
+
+// maxDeadlockRecoveries is the number of recoveries attempted in a row,
+// without any progress in between, before giving up
+const maxDeadlockRecoveries = 3
+
+func (r DeadlockRecovery) String() string {
+	return map[DeadlockRecovery]string{
+		StopOnDeadlock:  "stop",
+		YieldOnDeadlock: "yield",
+	}[r]
+}
+
+type unknownDeadlockRecoveryError struct {
+	name string
+}
+
+func (err unknownDeadlockRecoveryError) Error() string {
+	return fmt.Sprintf("Unknown deadlock recovery '%s'", err.name)
+}
+
+// ParseDeadlockRecovery returns the deadlock recovery with the given name
+func ParseDeadlockRecovery(name string) (DeadlockRecovery, error) {
+	for _, recovery := range []DeadlockRecovery{StopOnDeadlock, YieldOnDeadlock} {
+		if recovery.String() == name {
+			return recovery, nil
+		}
+	}
+	return StopOnDeadlock, unknownDeadlockRecoveryError{name: name}
+}
+
+// StuckForklift describes a forklift that was stuck when a deadlock was
+// detected
+type StuckForklift struct {
+	Name   string     `json:"name"`
+	Pos    pkg.Vector `json:"pos"`
+	Status string     `json:"status"`
+	Target string     `json:"target,omitempty"`
+}
+
+// Deadlock describes a detected deadlock. Since is the last round at which
+// the simulation made progress, and Parcels holds the names of the parcels
+// that were not delivered.
+type Deadlock struct {
+	Round      uint            `json:"round"`
+	Since      uint            `json:"since"`
+	Recoveries uint            `json:"recoveries"`
+	Forklifts  []StuckForklift `json:"forklifts"`
+	Parcels    []string        `json:"parcels"`
+}
+
+func (d Deadlock) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "deadlock: detected at round %d, no progress since round %d, %d recoveries\n", d.Round, d.Since, d.Recoveries)
+	for _, f := range d.Forklifts {
+		fmt.Fprintf(&b, "  %s at [%d,%d] %s", f.Name, f.Pos.X, f.Pos.Y, f.Status)
+		if f.Target != "" {
+			fmt.Fprintf(&b, ", targeting %s", f.Target)
+		}
+		fmt.Fprint(&b, "\n")
+	}
+	fmt.Fprintf(&b, "  parcels left: %s\n", strings.Join(d.Parcels, ", "))
+	return b.String()
+}
+
+// deadlockDetector watches the simulation state round after round. A
+// deadlock is detected when no parcel was grabbed nor dropped for a given
+// number of rounds, and the current state already occurred since, meaning
+// forklifts are either standing still or going round in circles.
+type deadlockDetector struct {
+	progress     uint
+	progressedAt uint
+	seen         map[uint64]bool
+	recoveries   uint
+	recoveredAt  uint
+}
+
+func newDeadlockDetector() deadlockDetector {
+	return deadlockDetector{seen: map[uint64]bool{}}
+}
+
+// progress returns a value that increases every time a parcel is grabbed or
+// dropped off
+func (s *Simulation) progress() uint {
+	var progress uint
+	for i := range s.parcels {
+		switch s.parcels[i].status {
+		case Carried:
+			progress++
+		case DroppedOff:
+			progress += 2
+		}
+	}
+	return progress
+}
+
+// stateHash returns a hash of the forklifts' and trucks' states
+func (s *Simulation) stateHash() uint64 {
+	h := fnv.New64a()
+	for i := range s.forklifts {
+		f := &s.forklifts[i]
+		fmt.Fprintf(h, "%d,%d,%d;", f.pos.X, f.pos.Y, f.status)
+	}
+	for i := range s.trucks {
+		t := &s.trucks[i]
+		fmt.Fprintf(h, "%d,%d,%d;", t.status, t.load, t.awayLeft)
+	}
+	return h.Sum64()
+}
+
+// isDeadlocked updates the detector with the simulation's current state, and
+// returns whether the simulation is deadlocked
+func (d *deadlockDetector) isDeadlocked(s *Simulation) bool {
+	if progress := s.progress(); progress != d.progress {
+		d.progress = progress
+		d.progressedAt = s.Round
+		d.seen = map[uint64]bool{}
+		d.recoveries = 0
+	}
+	hash := s.stateHash()
+	repeated := d.seen[hash]
+	d.seen[hash] = true
+	since := d.progressedAt
+	if d.recoveries > 0 && d.recoveredAt > since {
+		since = d.recoveredAt
+	}
+	return repeated && s.Round-since >= s.DeadlockRounds
+}
+
+// diagnose describes which forklifts and parcels are stuck
+func (d *deadlockDetector) diagnose(s *Simulation) Deadlock {
+	deadlock := Deadlock{
+		Round:      s.Round,
+		Since:      d.progressedAt,
+		Recoveries: d.recoveries,
+		Forklifts:  []StuckForklift{},
+		Parcels:    []string{},
+	}
+	for i := range s.forklifts {
+		f := &s.forklifts[i]
+		stuck := StuckForklift{
+			Name:   f.name,
+			Pos:    f.pos,
+			Status: f.status.String(),
+		}
+		if f.target.HasValue() {
+			switch target := f.target.Value().(type) {
+			case *Parcel:
+				stuck.Target = target.name
+			case *Truck:
+				stuck.Target = target.name
+			}
+		}
+		deadlock.Forklifts = append(deadlock.Forklifts, stuck)
+	}
+	for i := range s.parcels {
+		if s.parcels[i].status != DroppedOff {
+			deadlock.Parcels = append(deadlock.Parcels, s.parcels[i].name)
+		}
+	}
+	sort.Strings(deadlock.Parcels)
+	return deadlock
+}
+
+// detectDeadlock ends the simulation or tries to recover from a deadlock if
+// one is detected
+func (s *Simulation) detectDeadlock() {
+	if s.DeadlockRounds == 0 || !s.deadlocks.isDeadlocked(s) {
+		return
+	}
+	if s.DeadlockRecovery == YieldOnDeadlock && s.deadlocks.recoveries < maxDeadlockRecoveries {
+		s.deadlocks.recoveries++
+		s.deadlocks.recoveredAt = s.Round
+		s.deadlocks.seen = map[uint64]bool{}
+		logger.Warn("deadlock detected, forklifts yield (%d/%d)\n", s.deadlocks.recoveries, maxDeadlockRecoveries)
+		s.yield()
+		return
+	}
+	deadlock := s.deadlocks.diagnose(s)
+	s.Deadlock = &deadlock
+	s.Status = Deadlocked
+	logger.Warn("%s", deadlock.String())
+}
+
+// yield makes forklifts that are not grabbing nor dropping a parcel drop their
+// target, and step aside to a free tile, preferably one that isn't next to a
+// truck, so they leave room for others
+func (s *Simulation) yield() {
+	claimed := map[pkg.Vector]bool{}
+	for i := range s.forklifts {
+		claimed[s.forklifts[i].pos] = true
+	}
+	for i := range s.forklifts {
+		f := &s.forklifts[i]
+		if f.status != Empty && f.status != Loaded {
+			continue
+		}
+		f.dropTarget()
+		f.park(s)
+		var aside, nextToTruck []pkg.Vector
+		for _, direction := range []pkg.Vector{{X: 0, Y: -1}, {X: 1, Y: 0}, {X: 0, Y: 1}, {X: -1, Y: 0}} {
+			pos := f.pos.Add(direction)
+			if pos.X < 0 || pos.Y < 0 || !s.board.IsInBounds(uint(pos.X), uint(pos.Y)) {
+				continue
+			}
+			if claimed[pos] || s.board.At(uint(pos.X), uint(pos.Y)).Blocked || s.reservations.IsReserved(pos, s.Round, f.name) || s.reservations.IsReserved(pos, s.Round+1, f.name) {
+				continue
+			}
+			if s.isNextToTruck(pos) {
+				nextToTruck = append(nextToTruck, pos)
+			} else {
+				aside = append(aside, pos)
+			}
+		}
+		aside = append(aside, nextToTruck...)
+		if len(aside) > 0 {
+			claimed[aside[0]] = true
+			f.aside.Set(aside[0])
+			s.reservations.ReservePath(f.pos, aside[:1], s.Round, f.name)
+		}
+	}
+}
+
+func (s *Simulation) isNextToTruck(pos pkg.Vector) bool {
+	for i := range s.trucks {
+		if pos.SquaredDistance(s.trucks[i].pos) <= 1 {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package simulation
+
+import (
+	"testing"
+
+	"github.com/adrienlucbert/gofeur/config"
+	"github.com/adrienlucbert/gofeur/parsing"
+	"github.com/stretchr/testify/assert"
+)
+
+func runSimulationWithRecovery(t *testing.T, file string, recovery DeadlockRecovery) Simulation {
+	t.Helper()
+	config.Set("logLevel", "None")
+	gofeur, err := parsing.ParseInputFile(file)
+	assert.Nil(t, err)
+	sim := New(&gofeur, nil)
+	sim.DeadlockRounds = SuggestedDeadlockRounds
+	sim.DeadlockRecovery = recovery
+	sim.Start()
+	for sim.IsRunning() {
+		sim.Step()
+	}
+	return sim
+}
+
+func TestParseDeadlockRecovery(t *testing.T) {
+	recovery, err := ParseDeadlockRecovery("yield")
+	assert.Nil(t, err)
+	assert.Equal(t, YieldOnDeadlock, recovery)
+
+	_, err = ParseDeadlockRecovery("unknown")
+	assert.NotNil(t, err)
+}
+
+func TestDeadlockParcelTooHeavy(t *testing.T) {
+	for _, recovery := range []DeadlockRecovery{StopOnDeadlock, YieldOnDeadlock} {
+		sim := runSimulationWithRecovery(t, "testdata/heavy.txt", recovery)
+		assert.Equal(t, Deadlocked, sim.Status)
+		assert.Less(t, sim.Round, sim.MaxRound)
+		assert.NotNil(t, sim.Deadlock)
+		assert.Equal(t, []string{"colis_a"}, sim.Deadlock.Parcels)
+		assert.Len(t, sim.Deadlock.Forklifts, 1)
+		assert.Equal(t, "loaded", sim.Deadlock.Forklifts[0].Status)
+	}
+}
+
+func TestDeadlockBlockedDock(t *testing.T) {
+	sim := runSimulationWithRecovery(t, "testdata/dock.txt", StopOnDeadlock)
+	assert.Equal(t, Deadlocked, sim.Status)
+	assert.Equal(t, uint(0), sim.Deadlock.Recoveries)
+	assert.Equal(t, StuckForklift{Name: "transpalette_a", Pos: sim.forklifts[0].pos, Status: "loaded", Target: ""}, sim.Deadlock.Forklifts[0])
+
+	report := sim.Report()
+	assert.Equal(t, "deadlocked", report.Status)
+	assert.Equal(t, sim.Deadlock, report.Deadlock)
+	assert.Contains(t, report.String(), "deadlock: detected at round")
+}
+
+func TestDeadlockYield(t *testing.T) {
+	sim := runSimulationWithRecovery(t, "testdata/dock.txt", YieldOnDeadlock)
+	assert.Equal(t, Finished, sim.Status)
+	assert.Nil(t, sim.Deadlock)
+}
+
+func TestDeadlockDetectionDisabled(t *testing.T) {
+	config.Set("logLevel", "None")
+	gofeur, err := parsing.ParseInputFile("testdata/heavy.txt")
+	assert.Nil(t, err)
+	sim := New(&gofeur, nil)
+	sim.Start()
+	for sim.IsRunning() {
+		sim.Step()
+	}
+	assert.Equal(t, Unfinished, sim.Status)
+	assert.Equal(t, sim.MaxRound, sim.Round)
+}
@@ -0,0 +1,133 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// JSON names of the values of the enums of the package, so that traces and
+// reports don't depend on the order of their constants
+var (
+	eventKindNames = map[EventKind]string{
+		RoundStartEvent:   "round_start",
+		WaitEvent:         "wait",
+		GoEvent:           "go",
+		TakeEvent:         "take",
+		LeaveEvent:        "leave",
+		TruckWaitingEvent: "truck_waiting",
+		TruckGoneEvent:    "truck_gone",
+		RoundEndEvent:     "round_end",
+		EndEvent:          "end",
+	}
+	statusNames = map[Status]string{
+		Idle:       "idle",
+		Running:    "running",
+		Finished:   "finished",
+		Unfinished: "unfinished",
+		Deadlocked: "deadlocked",
+	}
+	forkliftStatusNames = map[ForkLiftStatus]string{
+		Empty:    "empty",
+		Grabbing: "grabbing",
+		Dropping: "dropping",
+		Loaded:   "loaded",
+	}
+	truckStatusNames = map[TruckStatus]string{
+		Loading: "loading",
+		Away:    "away",
+	}
+	parcelStatusNames = map[ParcelStatus]string{
+		StandingBy: "standing_by",
+		Targeted:   "targeted",
+		Carried:    "carried",
+		DroppedOff: "dropped_off",
+	}
+)
+
+type unknownEnumValueError struct {
+	value int
+}
+
+func (err unknownEnumValueError) Error() string {
+	return fmt.Sprintf("unknown enum value %d", err.value)
+}
+
+type unknownEnumNameError struct {
+	name string
+}
+
+func (err unknownEnumNameError) Error() string {
+	return fmt.Sprintf("unknown enum name '%s'", err.name)
+}
+
+func marshalEnum[T ~int](value T, names map[T]string) ([]byte, error) {
+	name, ok := names[value]
+	if !ok {
+		return nil, unknownEnumValueError{value: int(value)}
+	}
+	return json.Marshal(name)
+}
+
+func unmarshalEnum[T ~int](data []byte, names map[T]string, value *T) error {
+	var name string
+	if err := json.Unmarshal(data, &name); err != nil {
+		return err
+	}
+	for candidate, candidateName := range names {
+		if candidateName == name {
+			*value = candidate
+			return nil
+		}
+	}
+	return unknownEnumNameError{name: name}
+}
+
+// MarshalJSON implements json.Marshaler
+func (kind EventKind) MarshalJSON() ([]byte, error) {
+	return marshalEnum(kind, eventKindNames)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (kind *EventKind) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, eventKindNames, kind)
+}
+
+// MarshalJSON implements json.Marshaler
+func (s Status) MarshalJSON() ([]byte, error) {
+	return marshalEnum(s, statusNames)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *Status) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, statusNames, s)
+}
+
+// MarshalJSON implements json.Marshaler
+func (s ForkLiftStatus) MarshalJSON() ([]byte, error) {
+	return marshalEnum(s, forkliftStatusNames)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *ForkLiftStatus) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, forkliftStatusNames, s)
+}
+
+// MarshalJSON implements json.Marshaler
+func (s TruckStatus) MarshalJSON() ([]byte, error) {
+	return marshalEnum(s, truckStatusNames)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *TruckStatus) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, truckStatusNames, s)
+}
+
+// MarshalJSON implements json.Marshaler
+func (s ParcelStatus) MarshalJSON() ([]byte, error) {
+	return marshalEnum(s, parcelStatusNames)
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (s *ParcelStatus) UnmarshalJSON(data []byte) error {
+	return unmarshalEnum(data, parcelStatusNames, s)
+}
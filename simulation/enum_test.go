@@ -0,0 +1,43 @@
+package simulation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/adrienlucbert/gofeur/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnumJSON(t *testing.T) {
+	event := Event{Round: 3, Kind: TruckGoneEvent, Entity: "truck", Pos: pkg.Vector{X: 1, Y: 2}, Load: 100, Capacity: 500, Status: Deadlocked}
+	data, err := json.Marshal(event)
+	assert.Nil(t, err)
+	assert.JSONEq(t, `{"round":3,"kind":"truck_gone","entity":"truck","pos":{"x":1,"y":2},"load":100,"capacity":500,"status":"deadlocked"}`, string(data))
+	var decoded Event
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, event, decoded)
+
+	views := struct {
+		Forklift ForkliftView
+		Truck    TruckView
+		Parcel   ParcelView
+	}{
+		ForkliftView{Name: "forklift", Status: Dropping},
+		TruckView{Name: "truck", Status: Away},
+		ParcelView{Name: "parcel", Status: DroppedOff},
+	}
+	data, err = json.Marshal(views)
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"status":"dropping"`)
+	assert.Contains(t, string(data), `"status":"away"`)
+	assert.Contains(t, string(data), `"status":"dropped_off"`)
+	decodedViews := views
+	decodedViews.Forklift.Status, decodedViews.Truck.Status, decodedViews.Parcel.Status = Empty, Loading, StandingBy
+	assert.Nil(t, json.Unmarshal(data, &decodedViews))
+	assert.Equal(t, views, decodedViews)
+
+	assert.NotNil(t, json.Unmarshal([]byte(`{"kind":"fly"}`), &decoded))
+	assert.NotNil(t, json.Unmarshal([]byte(`{"kind":2}`), &decoded))
+	_, err = json.Marshal(Event{Kind: EventKind(42)})
+	assert.NotNil(t, err)
+}
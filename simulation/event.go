@@ -0,0 +1,120 @@
+package simulation
+
+import (
+	"fmt"
+
+	"github.com/adrienlucbert/gofeur/pkg"
+)
+
+// EventKind represents what an event is about
+type EventKind int
+
+const (
+	// RoundStartEvent is emitted before entities act
+	RoundStartEvent EventKind = iota
+	// WaitEvent is emitted when a forklift stays where it is
+	WaitEvent
+	// GoEvent is emitted when a forklift moves to a neighbour tile
+	GoEvent
+	// TakeEvent is emitted when a forklift grabs a parcel
+	TakeEvent
+	// LeaveEvent is emitted when a forklift drops a parcel in a truck
+	LeaveEvent
+	// TruckWaitingEvent is emitted when a truck is loading at the end of its
+	// turn
+	TruckWaitingEvent
+	// TruckGoneEvent is emitted when a truck is away at the end of its turn
+	TruckGoneEvent
+	// RoundEndEvent is emitted once every entity acted
+	RoundEndEvent
+	// EndEvent is emitted once the simulation is over
+	EndEvent
+)
+
+// String returns the name of the action in the text output
+func (kind EventKind) String() string {
+	return map[EventKind]string{
+		RoundStartEvent:   "ROUND START",
+		WaitEvent:         "WAIT",
+		GoEvent:           "GO",
+		TakeEvent:         "TAKE",
+		LeaveEvent:        "LEAVE",
+		TruckWaitingEvent: "WAITING",
+		TruckGoneEvent:    "GONE",
+		RoundEndEvent:     "ROUND END",
+		EndEvent:          "END",
+	}[kind]
+}
+
+// Event describes something that happened during a round, which number is
+// 1-based. Entity is the name of the forklift or truck that acted, and Pos its
+// position once it acted. Parcel and Color describe the parcel taken or left,
+// and Load and Capacity the load of trucks. Status is the status the
+// simulation ended with, for the end event, which Round is the number of
+// rounds played.
+type Event struct {
+	Round    uint       `json:"round"`
+	Kind     EventKind  `json:"kind"`
+	Entity   string     `json:"entity,omitempty"`
+	Pos      pkg.Vector `json:"pos"`
+	Parcel   string     `json:"parcel,omitempty"`
+	Color    string     `json:"color,omitempty"`
+	Load     uint       `json:"load,omitempty"`
+	Capacity uint       `json:"capacity,omitempty"`
+	Status   Status     `json:"status,omitempty"`
+}
+
+// Action describes the action of the event as in the text output, such as
+// GO [1,2] or WAITING 100/500
+func (event Event) Action() string {
+	switch event.Kind {
+	case GoEvent:
+		return fmt.Sprintf("%s [%d,%d]", event.Kind, event.Pos.X, event.Pos.Y)
+	case TakeEvent, LeaveEvent:
+		return fmt.Sprintf("%s %s %s", event.Kind, event.Parcel, event.Color)
+	case TruckWaitingEvent, TruckGoneEvent:
+		return fmt.Sprintf("%s %d/%d", event.Kind, event.Load, event.Capacity)
+	default:
+		return event.Kind.String()
+	}
+}
+
+// String returns the line of the text output describing the event, which is
+// empty for the end of a round, and an emoji telling how the simulation ended
+// for the end event
+func (event Event) String() string {
+	switch event.Kind {
+	case RoundStartEvent:
+		return fmt.Sprintf("tour %d", event.Round)
+	case RoundEndEvent:
+		return ""
+	case EndEvent:
+		return map[Status]string{
+			Running:    "😱",
+			Idle:       "😱",
+			Finished:   "😎",
+			Unfinished: "🙂",
+			Deadlocked: "😵",
+		}[event.Status]
+	default:
+		return fmt.Sprintf("%s %s", event.Entity, event.Action())
+	}
+}
+
+// Observer is notified of the events of a simulation, in the order they
+// happen
+type Observer interface {
+	OnEvent(event Event)
+}
+
+// Subscribe registers an observer, which is notified of the events of every
+// round played from then on
+func (s *Simulation) Subscribe(observer Observer) {
+	s.observers = append(s.observers, observer)
+}
+
+func (s *Simulation) emit(event Event) {
+	for _, observer := range s.observers {
+		observer.OnEvent(event)
+	}
+}
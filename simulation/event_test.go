@@ -0,0 +1,75 @@
+package simulation
+
+import (
+	"testing"
+
+	"github.com/adrienlucbert/gofeur/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+type recorder struct {
+	events []Event
+}
+
+func (r *recorder) OnEvent(event Event) {
+	r.events = append(r.events, event)
+}
+
+func TestEvents(t *testing.T) {
+	sim := newSimulation(t, "testdata/basic.txt")
+	r := &recorder{}
+	sim.Subscribe(r)
+	for sim.IsRunning() || sim.Status == Idle {
+		sim.Step()
+	}
+
+	counts := map[EventKind]int{}
+	round := uint(0)
+	for _, event := range r.events {
+		counts[event.Kind]++
+		switch event.Kind {
+		case RoundStartEvent:
+			round++
+		case TakeEvent, LeaveEvent:
+			assert.NotEmpty(t, event.Parcel)
+			assert.NotEmpty(t, event.Color)
+		case TruckWaitingEvent, TruckGoneEvent:
+			assert.Equal(t, "camion_a", event.Entity)
+			assert.Equal(t, uint(600), event.Capacity)
+		}
+		assert.Equal(t, round, event.Round)
+	}
+	assert.Equal(t, int(sim.Round), counts[RoundStartEvent])
+	assert.Equal(t, int(sim.Round), counts[RoundEndEvent])
+	assert.Equal(t, 3, counts[TakeEvent])
+	assert.Equal(t, 3, counts[LeaveEvent])
+	assert.Equal(t, 2*int(sim.Round), counts[WaitEvent]+counts[GoEvent]+counts[TakeEvent]+counts[LeaveEvent])
+	assert.Equal(t, int(sim.Round), counts[TruckWaitingEvent]+counts[TruckGoneEvent])
+
+	assert.Equal(t, 1, counts[EndEvent])
+
+	assert.Equal(t, Event{Round: 1, Kind: RoundStartEvent}, r.events[0])
+	assert.Equal(t, RoundEndEvent, r.events[len(r.events)-2].Kind)
+	assert.Equal(t, Event{Round: sim.Round, Kind: EndEvent, Status: Finished}, r.events[len(r.events)-1])
+}
+
+func TestEventString(t *testing.T) {
+	testCases := []struct {
+		event    Event
+		expected string
+	}{
+		{Event{Round: 3, Kind: RoundStartEvent}, "tour 3"},
+		{Event{Round: 3, Kind: WaitEvent, Entity: "forklift"}, "forklift WAIT"},
+		{Event{Round: 3, Kind: GoEvent, Entity: "forklift", Pos: pkg.Vector{X: 1, Y: 2}}, "forklift GO [1,2]"},
+		{Event{Round: 3, Kind: TakeEvent, Entity: "forklift", Parcel: "parcel", Color: "BLUE"}, "forklift TAKE parcel BLUE"},
+		{Event{Round: 3, Kind: LeaveEvent, Entity: "forklift", Parcel: "parcel", Color: "BLUE"}, "forklift LEAVE parcel BLUE"},
+		{Event{Round: 3, Kind: TruckWaitingEvent, Entity: "truck", Load: 100, Capacity: 500}, "truck WAITING 100/500"},
+		{Event{Round: 3, Kind: TruckGoneEvent, Entity: "truck", Load: 500, Capacity: 500}, "truck GONE 500/500"},
+		{Event{Round: 3, Kind: RoundEndEvent}, ""},
+		{Event{Round: 3, Kind: EndEvent, Status: Finished}, "😎"},
+		{Event{Round: 3, Kind: EndEvent, Status: Deadlocked}, "😵"},
+	}
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, testCase.event.String())
+	}
+}
@@ -3,7 +3,6 @@ package simulation
 import (
 	"errors"
 	"fmt"
-	"math"
 
 	"github.com/adrienlucbert/gofeur/logger"
 	"github.com/adrienlucbert/gofeur/optional"
@@ -26,63 +25,109 @@ const (
 	Loaded
 )
 
+func (s ForkLiftStatus) String() string {
+	return map[ForkLiftStatus]string{
+		Empty:    "empty",
+		Grabbing: "grabbing",
+		Dropping: "dropping",
+		Loaded:   "loaded",
+	}[s]
+}
+
 type forkliftAction interface {
-	fmt.Stringer
+	// fill sets the kind and details of the event describing the action
+	fill(event *Event)
 }
 
 type forkliftWaitAction struct{}
 
-func (a forkliftWaitAction) String() string {
-	return "WAIT"
+func (a forkliftWaitAction) fill(event *Event) {
+	event.Kind = WaitEvent
 }
 
 type forkliftGoAction struct {
 	pos pkg.Vector
 }
 
-func (a forkliftGoAction) String() string {
-	return fmt.Sprintf("GO [%d,%d]", a.pos.X, a.pos.Y)
+func (a forkliftGoAction) fill(event *Event) {
+	event.Kind = GoEvent
+	event.Pos = a.pos
 }
 
 type forkliftTakeAction struct {
-	parcel *parcel
+	parcel *Parcel
 }
 
-func (a forkliftTakeAction) String() string {
-	return fmt.Sprintf("TAKE %s %s", a.parcel.name, a.parcel.color)
+func (a forkliftTakeAction) fill(event *Event) {
+	event.Kind = TakeEvent
+	event.Parcel, event.Color = a.parcel.name, a.parcel.color
 }
 
 type forkliftLeaveAction struct {
-	parcel *parcel
+	parcel *Parcel
 }
 
-func (a forkliftLeaveAction) String() string {
-	return fmt.Sprintf("LEAVE %s %s", a.parcel.name, a.parcel.color)
+func (a forkliftLeaveAction) fill(event *Event) {
+	event.Kind = LeaveEvent
+	event.Parcel, event.Color = a.parcel.name, a.parcel.color
 }
 
-type forklift struct {
+// Forklift represents a forklift moving parcels from the warehouse to trucks
+type Forklift struct {
 	name   string
 	pos    pkg.Vector
-	parcel optional.Optional[*parcel]
+	parcel optional.Optional[*Parcel]
 	status ForkLiftStatus
 	target optional.Optional[prop]
 	path   optional.Optional[[]pkg.Vector]
+	// aside is the tile the forklift steps to to make room for others when
+	// recovering from a deadlock
+	aside optional.Optional[pkg.Vector]
+	// unreachable holds the trucks no path was found to since the forklift
+	// started looking for a truck this round
+	unreachable []*Truck
+
+	delivered          uint
+	waitRounds         uint
+	pathRecomputations uint
+}
+
+// Name returns the forklift's name
+func (f *Forklift) Name() string {
+	return f.name
+}
+
+// Pos returns the forklift's position
+func (f *Forklift) Pos() pkg.Vector {
+	return f.pos
+}
+
+// Status returns the forklift's status
+func (f *Forklift) Status() ForkLiftStatus {
+	return f.status
 }
 
-func newForkliftFromParsing(from *parsing.Forklift) forklift {
-	return forklift{
+// Parcel returns the parcel carried by the forklift, if any
+func (f *Forklift) Parcel() optional.Optional[*Parcel] {
+	return f.parcel
+}
+
+func newForkliftFromParsing(from *parsing.Forklift) Forklift {
+	return Forklift{
 		name:   from.Name,
 		pos:    pkg.Vector{X: int(from.X), Y: int(from.Y)},
-		parcel: optional.NewEmpty[*parcel](),
+		parcel: optional.NewEmpty[*Parcel](),
 		status: Empty,
 		target: optional.NewEmpty[prop](),
 		path:   optional.NewEmpty[[]pkg.Vector](),
+		aside:  optional.NewEmpty[pkg.Vector](),
 	}
 }
 
 var (
-	errParcelNotFound = errors.New("No closest parcel found")
-	errTruckNotFound  = errors.New("No closest truck found")
+	errParcelNotFound = errors.New("No parcel found")
+	errTruckNotFound  = errors.New("No truck found")
+	errEmptyPath      = errors.New("Empty path to target")
 )
 
 type pathToTargetError struct {
@@ -93,50 +138,131 @@ func (err pathToTargetError) Error() string {
 	return fmt.Sprintf("No path to target: %s", err.pathfinding.Error())
 }
 
-func (f *forklift) findPathToTarget(simulation *Simulation) error {
-	simulation.board.At(uint(f.target.Value().Pos().X), uint(f.target.Value().Pos().Y)).Blocked = false
-	path, err := pathfinding.Resolve(&simulation.board, f.pos, f.target.Value().Pos())
-	simulation.board.At(uint(f.target.Value().Pos().X), uint(f.target.Value().Pos().Y)).Blocked = true
+func (f *Forklift) findPathToTarget(simulation *Simulation) error {
+	target := f.target.Value().Pos()
+	simulation.reservations.Release(f.name)
+	simulation.board.At(uint(target.X), uint(target.Y)).Blocked = false
+	// other forklifts are avoided through their reservations
+	unblocked := simulation.unblockForklifts()
+	path, err := pathfinding.ResolveCooperative(&simulation.board, f.pos, target, simulation.Round, simulation.reservations, f.name)
+	simulation.reblock(unblocked)
+	simulation.board.At(uint(target.X), uint(target.Y)).Blocked = true
 	if err != nil {
+		if errors.Is(err, pathfinding.ErrPathReserved) {
+			// other forklifts are in the way, and this one is likely in
+			// theirs: staying until next round only lets them make room
+			f.hold(simulation)
+		} else {
+			f.park(simulation)
+		}
 		return pathToTargetError{pathfinding: err}
 	}
+	// the path is empty when the forklift already stands on its target
+	if len(path) == 0 {
+		f.park(simulation)
+		return errEmptyPath
+	}
+	simulation.reservations.ReservePath(f.pos, path[:len(path)-1], simulation.Round, f.name)
 	f.path.Set(path)
 	return nil
 }
 
-func (f *forklift) findClosestParcel(simulation *Simulation) error {
+// park releases the forklift's reservations, and reserves its current
+// position until it moves again
+func (f *Forklift) park(simulation *Simulation) {
+	simulation.reservations.Release(f.name)
+	simulation.reservations.Park(f.pos, simulation.Round, f.name)
+}
+
+// hold releases the forklift's reservations, and reserves its current
+// position until next round only, so that others may plan paths through it
+func (f *Forklift) hold(simulation *Simulation) {
+	simulation.reservations.Release(f.name)
+	simulation.reservations.Hold(f.pos, simulation.Round, f.name)
+}
+
+// isPathObstructed returns whether the next tile of the forklift's path is
+// blocked. The target, which is always blocked, isn't considered an obstacle.
+func (f *Forklift) isPathObstructed(simulation *Simulation) bool {
+	if len(f.path.Value()) <= 1 {
+		return false
+	}
+	next := f.path.Value()[0]
+	return next != f.pos && simulation.board.At(uint(next.X), uint(next.Y)).Blocked
+}
+
+// followPath moves the forklift to the next tile of its path, or waits if the
+// path requires so
+func (f *Forklift) followPath() forkliftAction {
+	dest := f.path.Value()[0]
+	f.path.Set(f.path.Value()[1:])
+	if dest == f.pos {
+		return forkliftWaitAction{}
+	}
+	f.pos = dest
+	return forkliftGoAction{dest}
+}
+
+func (f *Forklift) findParcel(simulation *Simulation) error {
 	// PERF: don't refetch target if not reached
-	if target := findClosestParcel(simulation.parcels, f.pos, math.MaxUint); target != nil {
+	if target := simulation.strategy.SelectParcel(simulation, f); target != nil {
 		target.status = Targeted
 		f.target.Set(target)
 	} else {
 		return errParcelNotFound
 	}
-	return f.findPathToTarget(simulation)
+	if err := f.findPathToTarget(simulation); err != nil {
+		f.unfocusParcel()
+		return err
+	}
+	return nil
 }
 
-func (f *forklift) findClosestTruck(simulation *Simulation) error {
-	// PERF: don't refetch target if not reached
-	if target := findClosestTruck(simulation.trucks, f.pos, f.parcel.Value().weight); target != nil {
+// findTruck asks the strategy for a truck to head to, and asks again as long
+// as it picks a truck no path leads to that wasn't already picked this round
+func (f *Forklift) findTruck(simulation *Simulation) error {
+	f.unreachable = f.unreachable[:0]
+	var err error = errTruckNotFound
+	for {
+		// PERF: don't refetch target if not reached
+		target := simulation.strategy.SelectTruck(simulation, f)
+		if target == nil || f.Unreachable(target) {
+			return err
+		}
 		f.target.Set(target)
 		f.focusTruck(target)
-	} else {
-		return errTruckNotFound
+		if err = f.findPathToTarget(simulation); err == nil {
+			return nil
+		}
+		f.unfocusTruck(target)
+		f.unreachable = append(f.unreachable, target)
+	}
+}
+
+// Unreachable returns whether the forklift couldn't find a path to the given
+// truck since it started looking for one this round
+func (f *Forklift) Unreachable(truck *Truck) bool {
+	for _, unreachable := range f.unreachable {
+		if unreachable == truck {
+			return true
+		}
 	}
-	return f.findPathToTarget(simulation)
+	return false
 }
 
-func (f *forklift) focusTruck(truck *truck) {
+func (f *Forklift) focusTruck(truck *Truck) {
 	truck.loadEstimate += f.parcel.Value().weight
 }
 
-func (f *forklift) unfocusTruck(truck *truck) {
+func (f *Forklift) unfocusTruck(truck *Truck) {
 	truck.loadEstimate -= f.parcel.Value().weight
+	f.target.Clear()
+	f.path.Clear()
 }
 
 var errForkliftAlreadyLoaded = errors.New("Forklift already loaded")
 
-func (f *forklift) startGrabbingParcel() error {
+func (f *Forklift) startGrabbingParcel() error {
 	if f.status == Loaded {
 		return errForkliftAlreadyLoaded
 	}
@@ -144,16 +270,16 @@ func (f *forklift) startGrabbingParcel() error {
 	return nil
 }
 
-func (f *forklift) finishGrabbingParcel() {
-	f.parcel.Set(f.target.Value().(*parcel))
+func (f *Forklift) finishGrabbingParcel() {
+	f.parcel.Set(f.target.Value().(*Parcel))
 	f.parcel.Value().status = Carried
 	f.target.Clear()
 	f.path.Clear()
 	f.status = Loaded
 }
 
-func (f *forklift) unfocusParcel() {
-	f.target.Value().(*parcel).status = StandingBy
+func (f *Forklift) unfocusParcel() {
+	f.target.Value().(*Parcel).status = StandingBy
 	f.target.Clear()
 	f.path.Clear()
 }
@@ -163,11 +289,11 @@ var (
 	errTruckFull     = errors.New("Truck is full")
 )
 
-func (f *forklift) startDroppingParcel() error {
+func (f *Forklift) startDroppingParcel() error {
 	if !f.parcel.HasValue() {
 		return errForkliftEmpty
 	}
-	if truck, ok := f.target.Value().(*truck); ok {
+	if truck, ok := f.target.Value().(*Truck); ok {
 		if truck.load+f.parcel.Value().weight > truck.capacity {
 			return errTruckFull
 		}
@@ -176,8 +302,10 @@ func (f *forklift) startDroppingParcel() error {
 	return nil
 }
 
-func (f *forklift) finishDroppingParcel() {
-	if truck, ok := f.target.Value().(*truck); ok {
+func (f *Forklift) finishDroppingParcel(simulation *Simulation) {
+	if truck, ok := f.target.Value().(*Truck); ok {
+		f.delivered++
+		simulation.lastDropRound = simulation.Round + 1
 		truck.load += f.parcel.Value().weight
 		f.target.Clear()
 		f.path.Clear()
@@ -187,12 +315,49 @@ func (f *forklift) finishDroppingParcel() {
 	}
 }
 
-func (f *forklift) seekParcel(simulation *Simulation) forkliftAction {
-	if !f.target.HasValue() || simulation.board.At(uint(f.path.Value()[0].X), uint(f.path.Value()[0].Y)).Blocked {
+// dropTarget gives up on the forklift's current target, if any
+func (f *Forklift) dropTarget() {
+	if !f.target.HasValue() {
+		return
+	}
+	switch target := f.target.Value().(type) {
+	case *Parcel:
+		f.unfocusParcel()
+	case *Truck:
+		f.unfocusTruck(target)
+	}
+}
+
+// stepAside moves the forklift to the tile it was asked to step aside to, if
+// that tile is still free
+func (f *Forklift) stepAside(simulation *Simulation) forkliftAction {
+	dest := f.aside.Value()
+	f.aside.Clear()
+	if simulation.board.At(uint(dest.X), uint(dest.Y)).Blocked {
+		return forkliftWaitAction{}
+	}
+	f.pos = dest
+	f.park(simulation)
+	return forkliftGoAction{dest}
+}
+
+// rebalance drops the forklift's target parcel if the strategy decided so
+func (f *Forklift) rebalance(simulation *Simulation, rebalancer Rebalancer) {
+	if f.status != Empty || !f.target.HasValue() {
+		return
+	}
+	if !rebalancer.KeepTarget(simulation, f, f.target.Value().(*Parcel)) {
+		f.unfocusParcel()
+	}
+}
+
+func (f *Forklift) seekParcel(simulation *Simulation) forkliftAction {
+	if !f.target.HasValue() || f.isPathObstructed(simulation) {
 		if f.target.HasValue() {
 			f.unfocusParcel()
+			f.pathRecomputations++
 		}
-		if err := f.findClosestParcel(simulation); err != nil {
+		if err := f.findParcel(simulation); err != nil {
 			logger.Debug("%s\n", err.Error())
 			return forkliftWaitAction{}
 		}
@@ -201,20 +366,18 @@ func (f *forklift) seekParcel(simulation *Simulation) forkliftAction {
 		if err := f.startGrabbingParcel(); err != nil {
 			logger.Debug("%s\n", err.Error())
 		}
-		return forkliftTakeAction{f.target.Value().(*parcel)}
+		return forkliftTakeAction{f.target.Value().(*Parcel)}
 	}
-	dest := f.path.Value()[0]
-	f.pos = dest
-	f.path.Set(f.path.Value()[1:])
-	return forkliftGoAction{dest}
+	return f.followPath()
 }
 
-func (f *forklift) seekTruck(simulation *Simulation) forkliftAction {
-	if !f.target.HasValue() || !f.target.Value().IsAvailable() || simulation.board.At(uint(f.path.Value()[0].X), uint(f.path.Value()[0].Y)).Blocked {
+func (f *Forklift) seekTruck(simulation *Simulation) forkliftAction {
+	if !f.target.HasValue() || !f.target.Value().IsAvailable() || f.isPathObstructed(simulation) {
 		if f.target.HasValue() {
-			f.unfocusTruck(f.target.Value().(*truck))
+			f.unfocusTruck(f.target.Value().(*Truck))
+			f.pathRecomputations++
 		}
-		if err := f.findClosestTruck(simulation); err != nil {
+		if err := f.findTruck(simulation); err != nil {
 			logger.Debug("%s\n", err.Error())
 			return forkliftWaitAction{}
 		}
@@ -225,26 +388,32 @@ func (f *forklift) seekTruck(simulation *Simulation) forkliftAction {
 		}
 		return forkliftLeaveAction{f.parcel.Value()}
 	}
-	dest := f.path.Value()[0]
-	f.pos = dest
-	f.path.Set(f.path.Value()[1:])
-	return forkliftGoAction{dest}
+	return f.followPath()
 }
 
-func (f *forklift) simulateRound(simulation *Simulation) {
+func (f *Forklift) simulateRound(simulation *Simulation) {
 	var action forkliftAction
+	if !f.path.HasValue() && !f.aside.HasValue() {
+		f.park(simulation)
+	}
 	switch f.status {
 	case Grabbing:
 		f.finishGrabbingParcel()
 	case Dropping:
-		f.finishDroppingParcel()
+		f.finishDroppingParcel(simulation)
 	}
-	switch f.status {
-	case Empty:
+	switch {
+	case f.aside.HasValue():
+		action = f.stepAside(simulation)
+	case f.status == Empty:
 		action = f.seekParcel(simulation)
-	case Grabbing:
-	case Loaded:
+	case f.status == Loaded:
 		action = f.seekTruck(simulation)
 	}
-	logger.Info("%s %s\n", f.name, action.String())
+	if _, ok := action.(forkliftWaitAction); ok {
+		f.waitRounds++
+	}
+	event := Event{Round: simulation.Round + 1, Entity: f.name, Pos: f.pos}
+	action.fill(&event)
+	simulation.emit(event)
 }
@@ -0,0 +1,31 @@
+package simulation
+
+import "math"
+
+// GreedyStrategy sends forklifts to the nearest available parcel, then to the
+// nearest truck that can hold it. Trucks leave once no forklift is heading
+// their way and no parcel that would fit is close enough to be brought before
+// they'd be back from delivery.
+type GreedyStrategy struct{}
+
+// SelectParcel implements Strategy.SelectParcel()
+func (GreedyStrategy) SelectParcel(simulation *Simulation, forklift *Forklift) *Parcel {
+	return findClosestParcel(simulation.parcels, forklift.pos, math.MaxUint)
+}
+
+// SelectTruck implements Strategy.SelectTruck()
+func (GreedyStrategy) SelectTruck(simulation *Simulation, forklift *Forklift) *Truck {
+	return findClosestTruck(simulation.trucks, forklift.pos, forklift.parcel.Value().weight)
+}
+
+// ShouldDepart implements Strategy.ShouldDepart()
+func (GreedyStrategy) ShouldDepart(simulation *Simulation, truck *Truck) bool {
+	availableLoad := truck.capacity - truck.loadEstimate
+	var parcelIsNearby bool
+	if target := findClosestParcel(simulation.parcels, truck.pos, availableLoad); target != nil {
+		// determine if a forklift would have roughly enough time to travel from
+		// the truck to nearest parcel and back in the time the truck would be away
+		parcelIsNearby = truck.pos.Distance(target.pos) <= float32(truck.awayTime)*2
+	}
+	return truck.load > 0 && truck.load == truck.loadEstimate && !parcelIsNearby
+}
@@ -1,26 +1,64 @@
 package simulation
 
 import (
+	"fmt"
+	"io"
 	"time"
+
+	"github.com/adrienlucbert/gofeur/logger"
 )
 
 // Layer is the application layer responsible for managing the game logic
 type Layer struct {
 	Simulation *Simulation
+	// ReportFormat is the format in which the end-of-game report is printed
+	// ("text" or "json"). No report is printed if empty.
+	ReportFormat string
+	// ReportWriter is where the end-of-game report is printed
+	ReportWriter io.Writer
 }
 
 // Attach initializes the LogicLayer
 func (layer *Layer) Attach() {
-	layer.Simulation.start()
+	layer.Simulation.Start()
 }
 
 // Update runs the game logic
 func (layer *Layer) Update(elapsedTime time.Duration) {
-	layer.Simulation.simulateRound()
+	layer.Simulation.Step()
 }
 
 // Detach handles the game end
 func (layer *Layer) Detach() {
-	layer.Simulation.terminate()
-	// TODO: print end-of-game data
+	if err := layer.printReport(); err != nil {
+		logger.Error("%s\n", err.Error())
+	}
+}
+
+type unknownReportFormatError struct {
+	format string
+}
+
+func (err unknownReportFormatError) Error() string {
+	return fmt.Sprintf("Unknown report format '%s'", err.format)
+}
+
+func (layer *Layer) printReport() error {
+	report := layer.Simulation.Report()
+	switch layer.ReportFormat {
+	case "":
+		return nil
+	case "text":
+		_, err := fmt.Fprint(layer.ReportWriter, report.String())
+		return err
+	case "json":
+		data, err := report.JSON()
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(layer.ReportWriter, "%s\n", data)
+		return err
+	default:
+		return unknownReportFormatError{format: layer.ReportFormat}
+	}
 }
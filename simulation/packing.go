@@ -0,0 +1,146 @@
+package simulation
+
+import (
+	"sort"
+
+	"github.com/adrienlucbert/gofeur/pkg"
+)
+
+func init() {
+	RegisterStrategy("packing", func() Strategy { return &PackingStrategy{} })
+}
+
+// PackingStrategy plans which truck each parcel goes to so that trucks leave
+// as full as possible. Parcels are spread among loading trucks by decreasing
+// weight, each going to the truck it fills best (best-fit decreasing).
+// Forklifts fetch parcels as in GreedyStrategy, and deliver them to their
+// planned truck, or to the best fitting reachable one if no path leads to it.
+//
+// A truck leaves once nothing more is planned for it. If some parcels don't fit
+// in any loading truck, waiting delays them until a truck leaves and comes
+// back: in that case, a truck also leaves when the weight it would deliver per
+// round by leaving right away is greater than the weight it would deliver per
+// round by waiting for its planned parcels.
+type PackingStrategy struct {
+	GreedyStrategy
+	plan        map[*Parcel]*Truck
+	plannedLoad map[*Truck]uint
+	// waitTime is the estimated number of rounds before the parcels planned
+	// for a truck are loaded
+	waitTime map[*Truck]uint
+	// overflow is whether some parcels don't fit in any loading truck
+	overflow bool
+}
+
+// Plan implements Planner.Plan()
+func (p *PackingStrategy) Plan(simulation *Simulation) {
+	p.plan = map[*Parcel]*Truck{}
+	p.plannedLoad = map[*Truck]uint{}
+	p.waitTime = map[*Truck]uint{}
+	p.overflow = false
+
+	trucks := []*Truck{}
+	for i := range simulation.trucks {
+		truck := &simulation.trucks[i]
+		if truck.IsAvailable() {
+			trucks = append(trucks, truck)
+			p.plannedLoad[truck] = truck.loadEstimate
+		}
+	}
+
+	parcels := []*Parcel{}
+	for i := range simulation.forklifts {
+		forklift := &simulation.forklifts[i]
+		if !forklift.parcel.HasValue() {
+			continue
+		}
+		// parcels already on their way are accounted for in the load estimate
+		if truck, ok := forklift.target.ValueOr(nil).(*Truck); ok {
+			p.plan[forklift.parcel.Value()] = truck
+		} else {
+			parcels = append(parcels, forklift.parcel.Value())
+		}
+	}
+	for i := range simulation.parcels {
+		if parcel := &simulation.parcels[i]; parcel.status == StandingBy || parcel.status == Targeted {
+			parcels = append(parcels, parcel)
+		}
+	}
+	sort.SliceStable(parcels, func(i, j int) bool {
+		return parcels[i].weight > parcels[j].weight
+	})
+
+	for _, parcel := range parcels {
+		truck := bestFittingTruck(trucks, parcel.pos, parcel.weight, func(t *Truck) uint {
+			return t.capacity - p.plannedLoad[t]
+		})
+		if truck == nil {
+			p.overflow = true
+			continue
+		}
+		p.plan[parcel] = truck
+		p.plannedLoad[truck] += parcel.weight
+		// forklifts roughly travel from the truck to the parcel and back
+		if wait := uint(2 * parcel.pos.Distance(truck.pos)); wait > p.waitTime[truck] {
+			p.waitTime[truck] = wait
+		}
+	}
+}
+
+// bestFittingTruck returns the truck which remaining capacity is the smallest
+// that can hold the given weight, the closest one to pos in case of equality
+func bestFittingTruck(trucks []*Truck, pos pkg.Vector, weight uint, remainingCapacity func(*Truck) uint) *Truck {
+	var best *Truck
+	var bestRemaining uint
+	var bestDistance float32
+	for _, truck := range trucks {
+		remaining := remainingCapacity(truck)
+		if remaining < weight {
+			continue
+		}
+		distance := pos.SquaredDistance(truck.pos)
+		if best == nil || remaining < bestRemaining || (remaining == bestRemaining && distance < bestDistance) {
+			best = truck
+			bestRemaining = remaining
+			bestDistance = distance
+		}
+	}
+	return best
+}
+
+// SelectTruck implements Strategy.SelectTruck()
+func (p *PackingStrategy) SelectTruck(simulation *Simulation, forklift *Forklift) *Truck {
+	parcel := forklift.parcel.Value()
+	if truck, ok := p.plan[parcel]; ok && truck.IsAvailable() && !forklift.Unreachable(truck) && truck.capacity-truck.loadEstimate >= parcel.weight {
+		return truck
+	}
+	trucks := []*Truck{}
+	for i := range simulation.trucks {
+		if truck := &simulation.trucks[i]; truck.IsAvailable() && !forklift.Unreachable(truck) {
+			trucks = append(trucks, truck)
+		}
+	}
+	return bestFittingTruck(trucks, forklift.pos, parcel.weight, func(t *Truck) uint {
+		return t.capacity - t.loadEstimate
+	})
+}
+
+// ShouldDepart implements Strategy.ShouldDepart()
+func (p *PackingStrategy) ShouldDepart(simulation *Simulation, truck *Truck) bool {
+	if truck.load == 0 || truck.load != truck.loadEstimate {
+		return false
+	}
+	if p.plannedLoad == nil {
+		p.Plan(simulation)
+	}
+	planned, ok := p.plannedLoad[truck]
+	if !ok || planned <= truck.load {
+		return true
+	}
+	if !p.overflow {
+		return false
+	}
+	// Leaving now delivers load in awayTime rounds, waiting delivers the
+	// planned load in waitTime + awayTime rounds
+	return truck.load*(p.waitTime[truck]+truck.awayTime) >= planned*truck.awayTime
+}
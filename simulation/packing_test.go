@@ -0,0 +1,53 @@
+package simulation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackingStrategyFillsTrucks(t *testing.T) {
+	strategy, err := NewStrategy("packing")
+	assert.Nil(t, err)
+	packingSim := runSimulationWith(t, "testdata/packing.txt", strategy)
+	greedySim := runSimulation(t, "testdata/packing.txt")
+	packing := packingSim.Report()
+	greedy := greedySim.Report()
+
+	assert.Equal(t, "finished", packing.Status)
+	assert.Equal(t, greedy.ParcelsDelivered, packing.ParcelsDelivered)
+	assert.LessOrEqual(t, packing.Rounds, greedy.Rounds)
+	assert.Greater(t, packing.AverageFillRatio, greedy.AverageFillRatio)
+	assert.Equal(t, []float64{1}, packing.Trucks[0].FillRatios)
+}
+
+func TestPackingStrategyFallsBackOnUnreachableTruck(t *testing.T) {
+	for _, file := range []string{
+		// each parcel best fits the truck at the other end of a narrow aisle
+		"testdata/aisle.txt",
+		// the parcel best fits a truck surrounded by obstacles
+		"testdata/walled.txt",
+	} {
+		strategy, err := NewStrategy("packing")
+		assert.Nil(t, err)
+		sim := runSimulationWith(t, file, strategy)
+
+		assert.Equal(t, Finished, sim.Status, file)
+		assert.Equal(t, uint(0), sim.remainingParcels(), file)
+	}
+}
+
+func TestBestFittingTruck(t *testing.T) {
+	trucks := []*Truck{
+		{name: "a", capacity: 1000, loadEstimate: 0},
+		{name: "b", capacity: 1000, loadEstimate: 700},
+		{name: "c", capacity: 1000, loadEstimate: 900},
+	}
+	remaining := func(t *Truck) uint {
+		return t.capacity - t.loadEstimate
+	}
+	assert.Equal(t, "b", bestFittingTruck(trucks, trucks[0].pos, 200, remaining).name)
+	assert.Equal(t, "c", bestFittingTruck(trucks, trucks[0].pos, 100, remaining).name)
+	assert.Equal(t, "a", bestFittingTruck(trucks, trucks[0].pos, 500, remaining).name)
+	assert.Nil(t, bestFittingTruck(trucks, trucks[0].pos, 1500, remaining))
+}
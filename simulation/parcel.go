@@ -21,29 +21,66 @@ const (
 	DroppedOff
 )
 
-type parcel struct {
+func (s ParcelStatus) String() string {
+	return map[ParcelStatus]string{
+		StandingBy: "standing by",
+		Targeted:   "targeted",
+		Carried:    "carried",
+		DroppedOff: "dropped off",
+	}[s]
+}
+
+// Parcel represents a parcel to be loaded in a truck
+type Parcel struct {
 	name   string
 	pos    pkg.Vector
 	color  string
+	glyph  rune
 	weight uint
 	status ParcelStatus
 }
 
 // Implement prop.Pos()
-func (p *parcel) Pos() pkg.Vector {
+func (p *Parcel) Pos() pkg.Vector {
 	return p.pos
 }
 
 // Implement prop.IsAvailable()
-func (p *parcel) IsAvailable() bool {
+func (p *Parcel) IsAvailable() bool {
 	return p.status == StandingBy
 }
 
-func newParcelFromParsing(from *parsing.Parcel) parcel {
-	return parcel{
+// Name returns the parcel's name
+func (p *Parcel) Name() string {
+	return p.name
+}
+
+// Color returns the parcel's color
+func (p *Parcel) Color() string {
+	return p.color
+}
+
+// Glyph returns the character the parcel's class is displayed with
+func (p *Parcel) Glyph() rune {
+	return p.glyph
+}
+
+// Weight returns the parcel's weight
+func (p *Parcel) Weight() uint {
+	return p.weight
+}
+
+// Status returns the parcel's status
+func (p *Parcel) Status() ParcelStatus {
+	return p.status
+}
+
+func newParcelFromParsing(from *parsing.Parcel, class parsing.ParcelClass) Parcel {
+	return Parcel{
 		name:   from.Name,
 		pos:    pkg.Vector{X: int(from.X), Y: int(from.Y)},
 		color:  strings.ToUpper(from.Color),
+		glyph:  class.Glyph,
 		weight: uint(from.Weight),
 	}
 }
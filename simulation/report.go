@@ -0,0 +1,135 @@
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// TruckReport holds end-of-simulation data about a truck. WeightLoaded is the
+// weight that was loaded but not shipped yet, and FillRatios holds the ratio
+// of the truck's capacity that was used on each trip.
+type TruckReport struct {
+	Name          string    `json:"name"`
+	Trips         uint      `json:"trips"`
+	WeightShipped uint      `json:"weight_shipped"`
+	WeightLoaded  uint      `json:"weight_loaded"`
+	FillRatios    []float64 `json:"fill_ratios"`
+}
+
+// ForkliftReport holds end-of-simulation data about a forklift
+type ForkliftReport struct {
+	Name               string `json:"name"`
+	ParcelsDelivered   uint   `json:"parcels_delivered"`
+	WaitRounds         uint   `json:"wait_rounds"`
+	PathRecomputations uint   `json:"path_recomputations"`
+}
+
+// Report summarizes a simulation run. LastDropRound is the round at which the
+// last parcel was dropped off in a truck, or 0 if no parcel was dropped.
+// Deadlock describes the deadlock that ended the simulation, if any.
+type Report struct {
+	Status             string           `json:"status"`
+	Rounds             uint             `json:"rounds"`
+	ParcelsDelivered   uint             `json:"parcels_delivered"`
+	ParcelsLeft        uint             `json:"parcels_left"`
+	WeightDelivered    uint             `json:"weight_delivered"`
+	WeightPerRound     float64          `json:"weight_per_round"`
+	WeightShipped      uint             `json:"weight_shipped"`
+	TruckTrips         uint             `json:"truck_trips"`
+	AverageFillRatio   float64          `json:"average_fill_ratio"`
+	WaitRounds         uint             `json:"wait_rounds"`
+	PathRecomputations uint             `json:"path_recomputations"`
+	LastDropRound      uint             `json:"last_drop_round"`
+	Trucks             []TruckReport    `json:"trucks"`
+	Forklifts          []ForkliftReport `json:"forklifts"`
+	Deadlock           *Deadlock        `json:"deadlock,omitempty"`
+}
+
+// Report builds a report of the simulation in its current state
+func (s *Simulation) Report() Report {
+	r := Report{
+		Status:        s.Status.String(),
+		Rounds:        s.Round,
+		LastDropRound: s.lastDropRound,
+		Deadlock:      s.Deadlock,
+		Trucks:        make([]TruckReport, 0, len(s.trucks)),
+		Forklifts:     make([]ForkliftReport, 0, len(s.forklifts)),
+	}
+	for i := range s.parcels {
+		if s.parcels[i].status == DroppedOff {
+			r.ParcelsDelivered++
+			r.WeightDelivered += s.parcels[i].weight
+		} else {
+			r.ParcelsLeft++
+		}
+	}
+	var fillRatios float64
+	if r.Rounds > 0 {
+		r.WeightPerRound = float64(r.WeightDelivered) / float64(r.Rounds)
+	}
+	for i := range s.trucks {
+		t := &s.trucks[i]
+		tr := TruckReport{
+			Name:       t.name,
+			Trips:      uint(len(t.trips)),
+			FillRatios: make([]float64, 0, len(t.trips)),
+		}
+		for _, load := range t.trips {
+			tr.WeightShipped += load
+			tr.FillRatios = append(tr.FillRatios, float64(load)/float64(t.capacity))
+			fillRatios += float64(load) / float64(t.capacity)
+		}
+		if t.status == Loading {
+			tr.WeightLoaded = t.load
+		}
+		r.Trucks = append(r.Trucks, tr)
+		r.TruckTrips += tr.Trips
+		r.WeightShipped += tr.WeightShipped
+	}
+	if r.TruckTrips > 0 {
+		r.AverageFillRatio = fillRatios / float64(r.TruckTrips)
+	}
+	for i := range s.forklifts {
+		f := &s.forklifts[i]
+		r.Forklifts = append(r.Forklifts, ForkliftReport{
+			Name:               f.name,
+			ParcelsDelivered:   f.delivered,
+			WaitRounds:         f.waitRounds,
+			PathRecomputations: f.pathRecomputations,
+		})
+		r.WaitRounds += f.waitRounds
+		r.PathRecomputations += f.pathRecomputations
+	}
+	return r
+}
+
+func (r Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "status: %s\n", r.Status)
+	fmt.Fprintf(&b, "rounds: %d\n", r.Rounds)
+	fmt.Fprintf(&b, "parcels: %d delivered, %d left\n", r.ParcelsDelivered, r.ParcelsLeft)
+	fmt.Fprintf(&b, "weight delivered: %d (%.2f per round)\n", r.WeightDelivered, r.WeightPerRound)
+	fmt.Fprintf(&b, "last drop round: %d\n", r.LastDropRound)
+	fmt.Fprintf(&b, "trucks: %d trips, %d shipped, %.0f%% average fill\n", r.TruckTrips, r.WeightShipped, r.AverageFillRatio*100)
+	for _, t := range r.Trucks {
+		fills := make([]string, 0, len(t.FillRatios))
+		for _, ratio := range t.FillRatios {
+			fills = append(fills, fmt.Sprintf("%.0f%%", ratio*100))
+		}
+		fmt.Fprintf(&b, "  %s: %d trips, %d shipped, %d loaded, fill [%s]\n", t.Name, t.Trips, t.WeightShipped, t.WeightLoaded, strings.Join(fills, " "))
+	}
+	fmt.Fprintf(&b, "forklifts: %d wait rounds, %d path recomputations\n", r.WaitRounds, r.PathRecomputations)
+	for _, f := range r.Forklifts {
+		fmt.Fprintf(&b, "  %s: %d delivered, %d wait rounds, %d path recomputations\n", f.Name, f.ParcelsDelivered, f.WaitRounds, f.PathRecomputations)
+	}
+	if r.Deadlock != nil {
+		fmt.Fprint(&b, r.Deadlock.String())
+	}
+	return b.String()
+}
+
+// JSON returns the indented JSON encoding of the report
+func (r Report) JSON() ([]byte, error) {
+	return json.MarshalIndent(r, "", "  ")
+}
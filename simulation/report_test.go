@@ -0,0 +1,63 @@
+package simulation
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/adrienlucbert/gofeur/config"
+	"github.com/adrienlucbert/gofeur/parsing"
+	"github.com/stretchr/testify/assert"
+)
+
+func runSimulation(t *testing.T, file string) Simulation {
+	t.Helper()
+	return runSimulationWith(t, file, nil)
+}
+
+func runSimulationWith(t *testing.T, file string, strategy Strategy) Simulation {
+	t.Helper()
+	config.Set("logLevel", "None")
+	gofeur, err := parsing.ParseInputFile(file)
+	assert.Nil(t, err)
+	sim := New(&gofeur, strategy)
+	sim.Start()
+	for sim.IsRunning() {
+		sim.Step()
+	}
+	return sim
+}
+
+func TestReport(t *testing.T) {
+	sim := runSimulation(t, "testdata/basic.txt")
+	report := sim.Report()
+
+	assert.Equal(t, "finished", report.Status)
+	assert.Equal(t, uint(3), report.ParcelsDelivered)
+	assert.Equal(t, uint(0), report.ParcelsLeft)
+	assert.Equal(t, report.Rounds, report.LastDropRound)
+	assert.Len(t, report.Trucks, 1)
+	assert.Len(t, report.Forklifts, 2)
+
+	var delivered uint
+	for _, forklift := range report.Forklifts {
+		delivered += forklift.ParcelsDelivered
+	}
+	assert.Equal(t, report.ParcelsDelivered, delivered)
+
+	var shipped uint
+	for _, truck := range report.Trucks {
+		shipped += truck.WeightShipped + truck.WeightLoaded
+	}
+	assert.Equal(t, uint(800), shipped)
+}
+
+func TestReportJSON(t *testing.T) {
+	sim := runSimulation(t, "testdata/basic.txt")
+	report := sim.Report()
+
+	data, err := report.JSON()
+	assert.Nil(t, err)
+	var decoded Report
+	assert.Nil(t, json.Unmarshal(data, &decoded))
+	assert.Equal(t, report, decoded)
+}
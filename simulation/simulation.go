@@ -2,9 +2,12 @@
 package simulation
 
 import (
+	"context"
+
 	"github.com/adrienlucbert/gofeur/board"
 	"github.com/adrienlucbert/gofeur/logger"
 	"github.com/adrienlucbert/gofeur/parsing"
+	"github.com/adrienlucbert/gofeur/pathfinding"
 	"github.com/adrienlucbert/gofeur/pkg"
 )
 
@@ -20,8 +23,20 @@ const (
 	Finished
 	// Unfinished is Gofeur's state when it's over but parcels remains in the warehouse
 	Unfinished
+	// Deadlocked is Gofeur's state when it's over because forklifts were stuck
+	Deadlocked
 )
 
+func (s Status) String() string {
+	return map[Status]string{
+		Idle:       "idle",
+		Running:    "running",
+		Finished:   "finished",
+		Unfinished: "unfinished",
+		Deadlocked: "deadlocked",
+	}[s]
+}
+
 type prop interface {
 	Pos() pkg.Vector
 	IsAvailable() bool
@@ -29,13 +44,56 @@ type prop interface {
 
 // Simulation represents the simulation data
 type Simulation struct {
-	MaxRound  uint
-	Round     uint
-	Status    Status
+	MaxRound uint
+	Round    uint
+	Status   Status
+	// DeadlockRounds is the number of rounds without progress after which a
+	// repeating state is considered a deadlock. Detection is disabled if 0.
+	DeadlockRounds uint
+	// DeadlockRecovery is what happens when a deadlock is detected
+	DeadlockRecovery DeadlockRecovery
+	// Deadlock describes the deadlock that ended the simulation, if any
+	Deadlock  *Deadlock
 	board     board.Board
-	forklifts []forklift
-	parcels   []parcel
-	trucks    []truck
+	forklifts []Forklift
+	parcels   []Parcel
+	trucks    []Truck
+	strategy  Strategy
+	// reservations holds the tiles forklifts will go through in the next
+	// rounds, so they can avoid each other
+	reservations *pathfinding.ReservationTable
+	deadlocks    deadlockDetector
+	observers    []Observer
+
+	lastDropRound uint
+}
+
+// Forklifts returns the simulation's forklifts. The slice is the one the
+// simulation plays with, so that strategies can return pointers to its
+// elements: its elements must only be read through their methods, and must
+// never be replaced. Use Snapshot for a copy.
+func (s *Simulation) Forklifts() []Forklift {
+	return s.forklifts
+}
+
+// Parcels returns the simulation's parcels, with the same restrictions as
+// Forklifts
+func (s *Simulation) Parcels() []Parcel {
+	return s.parcels
+}
+
+// Trucks returns the simulation's trucks, with the same restrictions as
+// Forklifts
+func (s *Simulation) Trucks() []Truck {
+	return s.trucks
+}
+
+// Board returns a copy of the simulation's board, on which parcels, forklifts
+// and loading trucks are marked as blocked. Changing it doesn't affect the
+// simulation.
+func (s *Simulation) Board() *board.Board {
+	clone := s.board.Clone()
+	return &clone
 }
 
 // IsRunning returns whether or not the simulation is in the Running state
@@ -43,8 +101,8 @@ func (s *Simulation) IsRunning() bool {
 	return s.Status == Running
 }
 
-func findClosestParcel(parcels []parcel, pos pkg.Vector, maximumWeight uint) *parcel {
-	var closestParcel *parcel
+func findClosestParcel(parcels []Parcel, pos pkg.Vector, maximumWeight uint) *Parcel {
+	var closestParcel *Parcel
 	var closestParcelDistance float32
 	for i := range parcels {
 		parcel := &parcels[i]
@@ -64,9 +122,9 @@ func findClosestParcel(parcels []parcel, pos pkg.Vector, maximumWeight uint) *pa
 // NOTE: turns out the type-specific filter condition makes it difficult as
 // predicates can't be called with an interface as parameter
 // NOTE: giving `prop` a `isAvailable` method would solve this issue
-// NOTE: turns out it doesn't, at passing []parcel as []prop is impossible
-func findClosestTruck(trucks []truck, pos pkg.Vector, minimumCapacity uint) *truck {
-	var closestTruck *truck
+// NOTE: turns out it doesn't, at passing []Parcel as []prop is impossible
+func findClosestTruck(trucks []Truck, pos pkg.Vector, minimumCapacity uint) *Truck {
+	var closestTruck *Truck
 	var closestTruckDistance float32
 	for i := range trucks {
 		truck := &trucks[i]
@@ -82,25 +140,93 @@ func findClosestTruck(trucks []truck, pos pkg.Vector, minimumCapacity uint) *tru
 	return closestTruck
 }
 
-func (s *Simulation) start() {
+// Start resets the round counter and sets the simulation running
+func (s *Simulation) Start() {
 	s.Round = 0
 	s.Status = Running
 }
 
-// New initializes a Simulation object
-func New(gofeur *parsing.Simulation) Simulation {
+// RoundResult describes the outcome of a round
+type RoundResult struct {
+	// Round is the number of rounds played so far
+	Round uint
+	// Status is the simulation's status once the round is over
+	Status Status
+	// Delivered is the number of parcels dropped off in trucks during the
+	// round, and Remaining the number of parcels left in the warehouse
+	Delivered uint
+	Remaining uint
+}
+
+// Step plays a round, starting the simulation first if it's idle, and returns
+// its outcome. The end event is emitted after the round ending the simulation.
+// Once the simulation is over, it plays nothing, and only returns the
+// simulation's current state.
+func (s *Simulation) Step() RoundResult {
+	if s.Status == Idle {
+		s.Start()
+	}
+	remaining := s.remainingParcels()
+	if s.IsRunning() {
+		s.simulateRound()
+		if !s.IsRunning() {
+			s.emit(Event{Round: s.Round, Kind: EndEvent, Status: s.Status})
+		}
+	}
+	result := RoundResult{Round: s.Round, Status: s.Status, Remaining: s.remainingParcels()}
+	result.Delivered = remaining - result.Remaining
+	return result
+}
+
+// RunUntil plays rounds until the simulation is over, or ctx is done, in which
+// case the context's error is returned. It returns the outcome of the last
+// round played.
+func (s *Simulation) RunUntil(ctx context.Context) (RoundResult, error) {
+	for {
+		if err := ctx.Err(); err != nil {
+			return RoundResult{Round: s.Round, Status: s.Status, Remaining: s.remainingParcels()}, err
+		}
+		result := s.Step()
+		if !s.IsRunning() {
+			return result, nil
+		}
+	}
+}
+
+// New initializes a Simulation object. If strategy is nil, the default greedy
+// strategy is used.
+func New(gofeur *parsing.Simulation, strategy Strategy) Simulation {
 	s := Simulation{}
+	if strategy == nil {
+		strategy = GreedyStrategy{}
+	}
+	s.strategy = strategy
+	s.reservations = pathfinding.NewReservationTable()
 	s.MaxRound = uint(gofeur.Cycle)
+	s.deadlocks = newDeadlockDetector()
 	s.board = board.New(uint(gofeur.Warehouse.Width), uint(gofeur.Warehouse.Length))
+	for _, tile := range gofeur.Warehouse.Tiles {
+		s.board.At(uint(tile.X), uint(tile.Y)).Cost = uint(tile.Cost)
+		s.board.At(uint(tile.X), uint(tile.Y)).Directions = tile.Directions
+	}
+	for _, obstacle := range gofeur.Warehouse.Obstacles {
+		for _, cell := range obstacle.Cells() {
+			s.board.At(uint(cell.X), uint(cell.Y)).Obstacle = true
+		}
+	}
 	for i := range gofeur.Warehouse.Forklifts {
 		s.forklifts = append(s.forklifts, newForkliftFromParsing(&gofeur.Warehouse.Forklifts[i]))
 	}
 	for i := range gofeur.Warehouse.Parcels {
-		s.parcels = append(s.parcels, newParcelFromParsing(&gofeur.Warehouse.Parcels[i]))
+		class, _ := gofeur.Warehouse.ParcelClassOf(gofeur.Warehouse.Parcels[i])
+		s.parcels = append(s.parcels, newParcelFromParsing(&gofeur.Warehouse.Parcels[i], class))
 	}
 	for i := range gofeur.Warehouse.Trucks {
 		s.trucks = append(s.trucks, newTruckFromParsing(&gofeur.Warehouse.Trucks[i]))
 	}
+	for i := range s.forklifts {
+		s.forklifts[i].park(&s)
+	}
 	s.updateBoard()
 	return s
 }
@@ -112,11 +238,7 @@ func (s *Simulation) updateBoard() {
 			continue
 		}
 		s.board.At(uint(s.parcels[i].pos.X), uint(s.parcels[i].pos.Y)).Blocked = true
-		s.board.At(uint(s.parcels[i].pos.X), uint(s.parcels[i].pos.Y)).DebugChar = map[uint]rune{
-			100: '1',
-			200: '2',
-			500: '3',
-		}[s.parcels[i].weight]
+		s.board.At(uint(s.parcels[i].pos.X), uint(s.parcels[i].pos.Y)).DebugChar = s.parcels[i].glyph
 	}
 	for i := range s.forklifts {
 		s.board.At(uint(s.forklifts[i].pos.X), uint(s.forklifts[i].pos.Y)).Blocked = true
@@ -131,13 +253,38 @@ func (s *Simulation) updateBoard() {
 	}
 }
 
+// unblockForklifts unblocks the tiles forklifts stand on, and returns them so
+// they can be blocked again with reblock
+func (s *Simulation) unblockForklifts() []*board.Tile {
+	unblocked := []*board.Tile{}
+	for i := range s.forklifts {
+		tile := s.board.At(uint(s.forklifts[i].pos.X), uint(s.forklifts[i].pos.Y))
+		if tile.Blocked {
+			tile.Blocked = false
+			unblocked = append(unblocked, tile)
+		}
+	}
+	return unblocked
+}
+
+func (s *Simulation) reblock(tiles []*board.Tile) {
+	for _, tile := range tiles {
+		tile.Blocked = true
+	}
+}
+
 func (s *Simulation) areAnyParcelsLeft() bool {
+	return s.remainingParcels() > 0
+}
+
+func (s *Simulation) remainingParcels() uint {
+	var remaining uint
 	for i := range s.parcels {
 		if s.parcels[i].status != DroppedOff {
-			return true
+			remaining++
 		}
 	}
-	return false
+	return remaining
 }
 
 func (s *Simulation) simulateRound() {
@@ -145,7 +292,16 @@ func (s *Simulation) simulateRound() {
 		s.Status = Finished
 		return
 	}
-	logger.Info("tour %d\n", s.Round+1)
+	s.emit(Event{Round: s.Round + 1, Kind: RoundStartEvent})
+	s.reservations.Prune(s.Round)
+	if planner, ok := s.strategy.(Planner); ok {
+		planner.Plan(s)
+	}
+	if rebalancer, ok := s.strategy.(Rebalancer); ok {
+		for i := range s.forklifts {
+			s.forklifts[i].rebalance(s, rebalancer)
+		}
+	}
 	for i := range s.forklifts {
 		s.forklifts[i].simulateRound(s)
 	}
@@ -154,21 +310,13 @@ func (s *Simulation) simulateRound() {
 	}
 	s.updateBoard()
 	logger.Debug("%s\n", s.board.String())
-	logger.Info("\n")
+	s.emit(Event{Round: s.Round + 1, Kind: RoundEndEvent})
 
 	// Increment round and end simulation if needed
 	s.Round++
 	if s.Round >= s.MaxRound {
 		s.Status = Unfinished
+		return
 	}
-}
-
-func (s *Simulation) terminate() {
-	reaction := map[Status]string{
-		Running:    "😱",
-		Idle:       "😱",
-		Finished:   "😎",
-		Unfinished: "🙂",
-	}[s.Status]
-	logger.Info("%s\n", reaction)
+	s.detectDeadlock()
 }
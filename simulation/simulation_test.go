@@ -0,0 +1,124 @@
+package simulation
+
+import (
+	"context"
+	"testing"
+
+	"github.com/adrienlucbert/gofeur/config"
+	"github.com/adrienlucbert/gofeur/parsing"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeightedTilesSlowForkliftsDown(t *testing.T) {
+	basic := runSimulation(t, "testdata/basic.txt")
+	slow := runSimulation(t, "testdata/slow.txt")
+
+	assert.Equal(t, Finished, basic.Status)
+	assert.Equal(t, Finished, slow.Status)
+	assert.Greater(t, slow.Round, basic.Round)
+	assert.Equal(t, uint(3), slow.Board().At(2, 2).EnterCost())
+}
+
+func TestObstaclesArePermanentlyBlocked(t *testing.T) {
+	sim := runSimulation(t, "testdata/walls.txt")
+
+	assert.Equal(t, Finished, sim.Status)
+	for y := uint(0); y < 4; y++ {
+		assert.True(t, sim.Board().At(3, y).Obstacle)
+		assert.True(t, sim.Board().At(3, y).Blocked)
+	}
+	assert.False(t, sim.Board().At(3, 4).Blocked)
+}
+
+func TestFindPathToOwnTile(t *testing.T) {
+	sim := newSimulation(t, "testdata/basic.txt")
+	forklift := &sim.forklifts[0]
+	parcel := sim.parcels[0]
+	parcel.pos = forklift.pos
+	forklift.target.Set(&parcel)
+
+	assert.Equal(t, errEmptyPath, forklift.findPathToTarget(&sim))
+	assert.False(t, forklift.path.HasValue())
+}
+
+func TestForkliftsCrossInNarrowAisle(t *testing.T) {
+	// each parcel only fits in the truck at the other end of the aisle
+	strategy, err := NewStrategy("packing")
+	assert.Nil(t, err)
+	sim := runSimulationWith(t, "testdata/aisle.txt", strategy)
+	report := sim.Report()
+
+	assert.Equal(t, Finished, sim.Status)
+	assert.Equal(t, uint(200), report.Trucks[0].WeightShipped)
+	assert.Equal(t, uint(500), report.Trucks[1].WeightShipped)
+	for _, forklift := range report.Forklifts {
+		assert.Equal(t, uint(1), forklift.ParcelsDelivered)
+	}
+}
+
+func TestBoardIsACopy(t *testing.T) {
+	sim := newSimulation(t, "testdata/walls.txt")
+	sim.Board().At(3, 4).Blocked = true
+	assert.False(t, sim.Board().At(3, 4).Blocked)
+}
+
+func TestParcelClassesCatalogue(t *testing.T) {
+	gofeur, err := parsing.ParseInputFile("testdata/classes.txt")
+	assert.Nil(t, err)
+	sim := New(&gofeur, nil)
+
+	assert.Equal(t, 'f', sim.Board().At(1, 1).DebugChar)
+	assert.Equal(t, 'P', sim.Board().At(3, 2).DebugChar)
+	assert.Equal(t, uint(50), sim.Parcels()[0].Weight())
+	assert.Equal(t, uint(1500), sim.Parcels()[1].Weight())
+	assert.Equal(t, "PALLET", sim.Parcels()[1].Color())
+
+	sim = runSimulation(t, "testdata/classes.txt")
+	assert.Equal(t, Finished, sim.Status)
+	assert.Equal(t, uint(1550), sim.Report().WeightDelivered)
+}
+
+func newSimulation(t *testing.T, file string) Simulation {
+	t.Helper()
+	config.Set("logLevel", "None")
+	gofeur, err := parsing.ParseInputFile(file)
+	assert.Nil(t, err)
+	return New(&gofeur, nil)
+}
+
+func TestStep(t *testing.T) {
+	sim := newSimulation(t, "testdata/basic.txt")
+	assert.Equal(t, Idle, sim.Status)
+
+	result := sim.Step()
+	assert.Equal(t, RoundResult{Round: 1, Status: Running, Remaining: 3}, result)
+
+	var delivered uint
+	for sim.IsRunning() {
+		result = sim.Step()
+		delivered += result.Delivered
+		assert.Equal(t, sim.Round, result.Round)
+	}
+	assert.Equal(t, uint(3), delivered)
+	assert.Equal(t, Finished, result.Status)
+	assert.Equal(t, uint(0), result.Remaining)
+
+	assert.Equal(t, result, sim.Step())
+	ran := runSimulation(t, "testdata/basic.txt")
+	assert.Equal(t, ran.Report(), sim.Report())
+}
+
+func TestRunUntil(t *testing.T) {
+	sim := newSimulation(t, "testdata/basic.txt")
+	result, err := sim.RunUntil(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, Finished, result.Status)
+	assert.Equal(t, runSimulation(t, "testdata/basic.txt").Round, result.Round)
+
+	sim = newSimulation(t, "testdata/basic.txt")
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	result, err = sim.RunUntil(ctx)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, RoundResult{Status: Idle, Remaining: 3}, result)
+}
@@ -0,0 +1,105 @@
+package simulation
+
+import (
+	"github.com/adrienlucbert/gofeur/pkg"
+)
+
+// ParcelView is the state of a parcel at some round
+type ParcelView struct {
+	Name   string       `json:"name"`
+	Pos    pkg.Vector   `json:"pos"`
+	Color  string       `json:"color"`
+	Weight uint         `json:"weight"`
+	Status ParcelStatus `json:"status,omitempty"`
+}
+
+// ForkliftView is the state of a forklift at some round. Parcel is the name
+// of the parcel it carries, and Target the name of the parcel or truck it's
+// heading to, both empty if none. Path holds the tiles it will go through.
+type ForkliftView struct {
+	Name   string         `json:"name"`
+	Pos    pkg.Vector     `json:"pos"`
+	Status ForkLiftStatus `json:"status,omitempty"`
+	Parcel string         `json:"parcel,omitempty"`
+	Target string         `json:"target,omitempty"`
+	Path   []pkg.Vector   `json:"path,omitempty"`
+}
+
+// TruckView is the state of a truck at some round. AwayLeft is the number of
+// rounds before it's back, while it's away, and Trips the weight it carried on
+// each of its trips.
+type TruckView struct {
+	Name         string      `json:"name"`
+	Pos          pkg.Vector  `json:"pos"`
+	Status       TruckStatus `json:"status,omitempty"`
+	Capacity     uint        `json:"capacity"`
+	Load         uint        `json:"load,omitempty"`
+	LoadEstimate uint        `json:"load_estimate,omitempty"`
+	AwayLeft     uint        `json:"away_left,omitempty"`
+	Trips        []uint      `json:"trips,omitempty"`
+}
+
+// Snapshot is a copy of the state of a simulation, which isn't affected by
+// the following rounds, and can't affect them
+type Snapshot struct {
+	Round     uint           `json:"round"`
+	MaxRound  uint           `json:"max_round"`
+	Status    Status         `json:"status,omitempty"`
+	Width     uint           `json:"width"`
+	Length    uint           `json:"length"`
+	Parcels   []ParcelView   `json:"parcels"`
+	Forklifts []ForkliftView `json:"forklifts"`
+	Trucks    []TruckView    `json:"trucks"`
+}
+
+// Snapshot returns a copy of the simulation's current state
+func (s *Simulation) Snapshot() Snapshot {
+	snapshot := Snapshot{
+		Round:     s.Round,
+		MaxRound:  s.MaxRound,
+		Status:    s.Status,
+		Width:     s.board.Width(),
+		Length:    s.board.Height(),
+		Parcels:   make([]ParcelView, 0, len(s.parcels)),
+		Forklifts: make([]ForkliftView, 0, len(s.forklifts)),
+		Trucks:    make([]TruckView, 0, len(s.trucks)),
+	}
+	for i := range s.parcels {
+		p := &s.parcels[i]
+		snapshot.Parcels = append(snapshot.Parcels, ParcelView{Name: p.name, Pos: p.pos, Color: p.color, Weight: p.weight, Status: p.status})
+	}
+	for i := range s.forklifts {
+		snapshot.Forklifts = append(snapshot.Forklifts, s.forklifts[i].view())
+	}
+	for i := range s.trucks {
+		t := &s.trucks[i]
+		snapshot.Trucks = append(snapshot.Trucks, TruckView{
+			Name:         t.name,
+			Pos:          t.pos,
+			Status:       t.status,
+			Capacity:     t.capacity,
+			Load:         t.load,
+			LoadEstimate: t.loadEstimate,
+			AwayLeft:     t.awayLeft,
+			Trips:        append([]uint{}, t.trips...),
+		})
+	}
+	return snapshot
+}
+
+func (f *Forklift) view() ForkliftView {
+	view := ForkliftView{Name: f.name, Pos: f.pos, Status: f.status}
+	if f.parcel.HasValue() {
+		view.Parcel = f.parcel.Value().name
+	}
+	switch target := f.target.ValueOr(nil).(type) {
+	case *Parcel:
+		view.Target = target.name
+	case *Truck:
+		view.Target = target.name
+	}
+	if f.path.HasValue() {
+		view.Path = append([]pkg.Vector{}, f.path.Value()...)
+	}
+	return view
+}
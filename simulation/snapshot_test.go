@@ -0,0 +1,42 @@
+package simulation
+
+import (
+	"testing"
+
+	"github.com/adrienlucbert/gofeur/pkg"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnapshot(t *testing.T) {
+	sim := newSimulation(t, "testdata/basic.txt")
+	snapshot := sim.Snapshot()
+
+	assert.Equal(t, Idle, snapshot.Status)
+	assert.Equal(t, uint(100), snapshot.MaxRound)
+	assert.Equal(t, uint(5), snapshot.Width)
+	assert.Equal(t, uint(5), snapshot.Length)
+	assert.Equal(t, ParcelView{Name: "colis_a", Pos: pkg.Vector{X: 1, Y: 1}, Color: "YELLOW", Weight: 100, Status: StandingBy}, snapshot.Parcels[0])
+	assert.Equal(t, ForkliftView{Name: "transpalette_a", Pos: pkg.Vector{X: 0, Y: 2}, Status: Empty}, snapshot.Forklifts[0])
+	assert.Equal(t, TruckView{Name: "camion_a", Pos: pkg.Vector{X: 2, Y: 4}, Status: Loading, Capacity: 600, Trips: []uint{}}, snapshot.Trucks[0])
+
+	sim.Step()
+	snapshot = sim.Snapshot()
+	forklift := snapshot.Forklifts[0]
+	assert.Equal(t, uint(1), snapshot.Round)
+	assert.NotEmpty(t, forklift.Target)
+	assert.NotEmpty(t, forklift.Path)
+	assert.Equal(t, Targeted, snapshot.Parcels[0].Status)
+
+	// snapshots are copies
+	forklift.Path[0] = pkg.Vector{X: -1, Y: -1}
+	assert.NotEqual(t, forklift.Path[0], sim.Snapshot().Forklifts[0].Path[0])
+
+	for sim.IsRunning() {
+		sim.Step()
+	}
+	snapshot = sim.Snapshot()
+	assert.Equal(t, Finished, snapshot.Status)
+	for _, parcel := range snapshot.Parcels {
+		assert.Equal(t, DroppedOff, parcel.Status)
+	}
+}
@@ -0,0 +1,78 @@
+package simulation
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Strategy decides how forklifts and trucks behave during the simulation. It
+// is consulted whenever a forklift needs a new target, and every round for each
+// loading truck.
+type Strategy interface {
+	// SelectParcel returns the parcel an empty forklift should fetch, or nil if
+	// there is none
+	SelectParcel(simulation *Simulation, forklift *Forklift) *Parcel
+	// SelectTruck returns the truck a loaded forklift should drop its parcel
+	// in, or nil if there is none. If no path leads to the returned truck, it
+	// is consulted again in the same round, until it returns nil or a truck
+	// for which Forklift.Unreachable is true.
+	SelectTruck(simulation *Simulation, forklift *Forklift) *Truck
+	// ShouldDepart returns whether a loading truck should leave for delivery
+	ShouldDepart(simulation *Simulation, truck *Truck) bool
+}
+
+// Planner is implemented by strategies that plan ahead. Plan is called at the
+// start of every round, before any forklift or truck is simulated.
+type Planner interface {
+	Strategy
+	Plan(simulation *Simulation)
+}
+
+// Rebalancer is implemented by strategies that may take back the parcel an
+// empty forklift is heading to. It is consulted every round, and forklifts
+// drop their target parcel if KeepTarget returns false.
+type Rebalancer interface {
+	Strategy
+	KeepTarget(simulation *Simulation, forklift *Forklift, parcel *Parcel) bool
+}
+
+// DefaultStrategy is the name of the strategy used when none is specified
+const DefaultStrategy = "greedy"
+
+var strategies = map[string]func() Strategy{
+	DefaultStrategy: func() Strategy { return GreedyStrategy{} },
+}
+
+// RegisterStrategy makes a strategy available under the given name. Registering
+// a strategy under an already used name replaces the previous one.
+func RegisterStrategy(name string, factory func() Strategy) {
+	strategies[name] = factory
+}
+
+// StrategyNames returns the sorted names of the registered strategies
+func StrategyNames() []string {
+	names := make([]string, 0, len(strategies))
+	for name := range strategies {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type unknownStrategyError struct {
+	name string
+}
+
+func (err unknownStrategyError) Error() string {
+	return fmt.Sprintf("Unknown strategy '%s' (available: %s)", err.name, strings.Join(StrategyNames(), ", "))
+}
+
+// NewStrategy instantiates the strategy registered under the given name
+func NewStrategy(name string) (Strategy, error) {
+	factory, ok := strategies[name]
+	if !ok {
+		return nil, unknownStrategyError{name: name}
+	}
+	return factory(), nil
+}
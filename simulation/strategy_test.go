@@ -0,0 +1,38 @@
+package simulation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type lazyStrategy struct {
+	GreedyStrategy
+}
+
+func (lazyStrategy) SelectParcel(*Simulation, *Forklift) *Parcel {
+	return nil
+}
+
+func TestNewStrategy(t *testing.T) {
+	strategy, err := NewStrategy(DefaultStrategy)
+	assert.Nil(t, err)
+	assert.Equal(t, GreedyStrategy{}, strategy)
+
+	_, err = NewStrategy("unknown")
+	assert.NotNil(t, err)
+}
+
+func TestRegisterStrategy(t *testing.T) {
+	RegisterStrategy("lazy", func() Strategy { return lazyStrategy{} })
+	defer delete(strategies, "lazy")
+
+	assert.Contains(t, StrategyNames(), "lazy")
+	strategy, err := NewStrategy("lazy")
+	assert.Nil(t, err)
+
+	sim := runSimulationWith(t, "testdata/basic.txt", strategy)
+	report := sim.Report()
+	assert.Equal(t, "unfinished", report.Status)
+	assert.Equal(t, uint(0), report.ParcelsDelivered)
+}
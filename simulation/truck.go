@@ -1,7 +1,6 @@
 package simulation
 
 import (
-	"github.com/adrienlucbert/gofeur/logger"
 	"github.com/adrienlucbert/gofeur/parsing"
 	"github.com/adrienlucbert/gofeur/pkg"
 )
@@ -16,7 +15,15 @@ const (
 	Away
 )
 
-type truck struct {
+func (s TruckStatus) String() string {
+	return map[TruckStatus]string{
+		Loading: "loading",
+		Away:    "away",
+	}[s]
+}
+
+// Truck represents a truck being loaded with parcels by forklifts
+type Truck struct {
 	name         string
 	pos          pkg.Vector
 	capacity     uint
@@ -25,20 +32,52 @@ type truck struct {
 	status       TruckStatus
 	awayTime     uint
 	awayLeft     uint
+	trips        []uint
 }
 
 // Implement prop.Pos()
-func (t *truck) Pos() pkg.Vector {
+func (t *Truck) Pos() pkg.Vector {
 	return t.pos
 }
 
 // Implement prop.IsAvailable()
-func (t *truck) IsAvailable() bool {
+func (t *Truck) IsAvailable() bool {
 	return t.status == Loading
 }
 
-func newTruckFromParsing(from *parsing.Truck) truck {
-	return truck{
+// Name returns the truck's name
+func (t *Truck) Name() string {
+	return t.name
+}
+
+// Capacity returns the maximum weight the truck can carry
+func (t *Truck) Capacity() uint {
+	return t.capacity
+}
+
+// Load returns the weight currently loaded in the truck
+func (t *Truck) Load() uint {
+	return t.load
+}
+
+// LoadEstimate returns the weight the truck will carry once the forklifts
+// targeting it drop their parcel
+func (t *Truck) LoadEstimate() uint {
+	return t.loadEstimate
+}
+
+// AwayTime returns the number of rounds the truck takes to deliver its load
+func (t *Truck) AwayTime() uint {
+	return t.awayTime
+}
+
+// Status returns the truck's status
+func (t *Truck) Status() TruckStatus {
+	return t.status
+}
+
+func newTruckFromParsing(from *parsing.Truck) Truck {
+	return Truck{
 		name:         from.Name,
 		pos:          pkg.Vector{X: int(from.X), Y: int(from.Y)},
 		capacity:     uint(from.MaxWeight),
@@ -50,22 +89,16 @@ func newTruckFromParsing(from *parsing.Truck) truck {
 	}
 }
 
-func (t *truck) startDelivery() {
+func (t *Truck) startDelivery() {
 	t.status = Away
 	t.awayLeft = t.awayTime
+	t.trips = append(t.trips, t.load)
 }
 
-func (t *truck) simulateRound(simulation *Simulation) {
+func (t *Truck) simulateRound(simulation *Simulation) {
 	switch t.status {
 	case Loading:
-		availableLoad := t.capacity - t.loadEstimate
-		var parcelIsNearby bool
-		if target := findClosestParcel(simulation.parcels, t.pos, availableLoad); target != nil {
-			// determine if a forklift would have roughly enough time to travel from
-			// the truck to nearest parcel and back in the time the truck would be away
-			parcelIsNearby = t.pos.Distance(target.pos) <= float32(t.awayTime)*2
-		}
-		if t.load > 0 && t.load == t.loadEstimate && !parcelIsNearby {
+		if simulation.strategy.ShouldDepart(simulation, t) {
 			t.startDelivery()
 		}
 	case Away:
@@ -76,9 +109,12 @@ func (t *truck) simulateRound(simulation *Simulation) {
 			t.status = Loading
 		}
 	}
-	action := map[TruckStatus]string{
-		Loading: "WAITING",
-		Away:    "GONE",
-	}[t.status]
-	logger.Info("%s %s %d/%d\n", t.name, action, t.load, t.capacity)
+	simulation.emit(Event{
+		Round:    simulation.Round + 1,
+		Kind:     map[TruckStatus]EventKind{Loading: TruckWaitingEvent, Away: TruckGoneEvent}[t.status],
+		Entity:   t.name,
+		Pos:      t.pos,
+		Load:     t.load,
+		Capacity: t.capacity,
+	})
 }
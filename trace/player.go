@@ -0,0 +1,155 @@
+package trace
+
+import (
+	"errors"
+
+	"github.com/adrienlucbert/gofeur/simulation"
+)
+
+// Player browses the rounds of a trace, forwards or backwards, without
+// playing them again. The state of a round is rebuilt from the keyframe
+// preceding it, and the events of the rounds in between, so that only these
+// records are read.
+type Player struct {
+	header   Header
+	records  Records
+	replayer replayer
+	position int
+	record   Record
+	state    State
+	err      error
+}
+
+var (
+	errEmptyTrace      = errors.New("the trace has no record")
+	errMissingKeyframe = errors.New("the first record of the trace isn't a keyframe")
+)
+
+// NewPlayer returns a player showing the initial state of the trace which
+// header and records are given
+func NewPlayer(header Header, records Records) (*Player, error) {
+	if records.Len() == 0 {
+		return nil, errEmptyTrace
+	}
+	first, err := records.Record(0)
+	if err != nil {
+		return nil, err
+	}
+	if first.State == nil {
+		return nil, errMissingKeyframe
+	}
+	scenario, err := header.Simulation()
+	if err != nil {
+		return nil, err
+	}
+	player := &Player{header: header, records: records, replayer: newReplayer(&scenario)}
+	player.show(0)
+	return player, player.err
+}
+
+// Round returns the number of rounds played in the state shown
+func (p *Player) Round() uint {
+	return p.state.Round
+}
+
+// LastRound returns the number of rounds played once the trace is over
+func (p *Player) LastRound() uint {
+	return uint(p.records.Len() - 1)
+}
+
+// Snapshot returns the state shown
+func (p *Player) Snapshot() simulation.Snapshot {
+	return p.state.clone().snapshot(p.header)
+}
+
+// Events returns the events of the round leading to the state shown, which
+// are none for the initial state
+func (p *Player) Events() []simulation.Event {
+	return p.record.Events
+}
+
+// Err returns the first error met reading the records of the trace, after
+// which the state shown doesn't change anymore
+func (p *Player) Err() error {
+	return p.err
+}
+
+// Step shows the following round, and returns false if the trace is over
+func (p *Player) Step() bool {
+	if p.position == p.records.Len()-1 {
+		return false
+	}
+	record, ok := p.read(p.position + 1)
+	if !ok {
+		return false
+	}
+	p.play(&p.state, record)
+	p.position, p.record = p.position+1, record
+	return true
+}
+
+// StepBack shows the previous round, and returns false if the initial state
+// is shown
+func (p *Player) StepBack() bool {
+	if p.position == 0 {
+		return false
+	}
+	return p.show(p.position - 1)
+}
+
+// Seek shows the state once the given number of rounds is played, or the last
+// state of the trace if it has fewer rounds
+func (p *Player) Seek(round uint) {
+	if round > p.LastRound() {
+		round = p.LastRound()
+	}
+	p.show(int(round))
+}
+
+// show rebuilds the state of the record at position from the keyframe
+// preceding it, and returns false if a record couldn't be read
+func (p *Player) show(position int) bool {
+	keyframe := position
+	if interval := int(p.header.KeyframeInterval); interval > 0 {
+		keyframe -= position % interval
+	}
+	record, ok := p.read(keyframe)
+	// keyframes are looked for before if the header is wrong, the first
+	// record being one
+	for ok && record.State == nil {
+		keyframe--
+		record, ok = p.read(keyframe)
+	}
+	var state State
+	for i := keyframe; ok; i++ {
+		p.play(&state, record)
+		if i == position {
+			p.position, p.record, p.state = position, record, state
+			return true
+		}
+		record, ok = p.read(i + 1)
+	}
+	return false
+}
+
+// read returns the record at position, and false if it couldn't be read, or
+// a record couldn't be read before
+func (p *Player) read(position int) (Record, bool) {
+	if p.err != nil {
+		return Record{}, false
+	}
+	record, err := p.records.Record(position)
+	p.err = err
+	return record, err == nil
+}
+
+// play changes state to the state of the record following it
+func (p *Player) play(state *State, record Record) {
+	if record.State != nil {
+		*state = record.State.clone()
+		return
+	}
+	for _, event := range record.Events {
+		p.replayer.apply(state, event)
+	}
+}
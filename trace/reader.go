@@ -0,0 +1,199 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Reader reads a trace record by record, so that long traces needn't be
+// loaded at once
+type Reader struct {
+	Header  Header
+	decoder *json.Decoder
+}
+
+var errMissingHeader = errors.New("the trace has no header")
+
+type traceError struct {
+	line int
+	err  error
+}
+
+func (err traceError) Error() string {
+	return fmt.Sprintf("line %d of the trace: %s", err.line, err.err.Error())
+}
+
+// NewReader reads the header of the trace read from reader, and checks its
+// format is supported
+func NewReader(reader io.Reader) (*Reader, error) {
+	r := &Reader{decoder: json.NewDecoder(reader)}
+	header, err := readHeader(r.decoder)
+	if err != nil {
+		return nil, err
+	}
+	r.Header = header
+	return r, nil
+}
+
+func readHeader(decoder *json.Decoder) (Header, error) {
+	var header Header
+	if err := decoder.Decode(&header); err != nil {
+		if err == io.EOF {
+			return Header{}, errMissingHeader
+		}
+		return Header{}, traceError{line: 1, err: err}
+	}
+	if header.FormatVersion != FormatVersion {
+		return Header{}, unsupportedFormatError{version: header.FormatVersion}
+	}
+	return header, nil
+}
+
+// Next returns the following record, or io.EOF once every record was read
+func (r *Reader) Next() (Record, error) {
+	var record Record
+	err := r.decoder.Decode(&record)
+	return record, err
+}
+
+// Records gives the records of a trace by number, from 0 to Len() - 1
+type Records interface {
+	Len() int
+	Record(i int) (Record, error)
+}
+
+// Trace is a trace loaded in memory
+type Trace struct {
+	Header  Header
+	Records []Record
+}
+
+// Len implements Records
+func (trace Trace) Len() int {
+	return len(trace.Records)
+}
+
+// Record implements Records
+func (trace Trace) Record(i int) (Record, error) {
+	return trace.Records[i], nil
+}
+
+// Read loads the whole trace read from reader
+func Read(reader io.Reader) (Trace, error) {
+	r, err := NewReader(reader)
+	if err != nil {
+		return Trace{}, err
+	}
+	trace := Trace{Header: r.Header}
+	for {
+		record, err := r.Next()
+		if err == io.EOF {
+			return trace, nil
+		}
+		if err != nil {
+			return Trace{}, traceError{line: len(trace.Records) + 2, err: err}
+		}
+		trace.Records = append(trace.Records, record)
+	}
+}
+
+type traceOpenError struct {
+	file string
+	err  error
+}
+
+func (err traceOpenError) Error() string {
+	return fmt.Sprintf("failed to open the trace file %s: %s", err.file, err.err.Error())
+}
+
+// File is a trace file which records are only read when asked for, so that
+// long traces can be browsed without loading them
+type File struct {
+	Header  Header
+	handle  *os.File
+	offsets []int64
+}
+
+// Open reads the header of the trace file, and indexes its records
+func Open(file string) (*File, error) {
+	handle, err := os.Open(file)
+	if err != nil {
+		return nil, traceOpenError{file: file, err: err}
+	}
+	f := &File{handle: handle}
+	if err := f.index(); err != nil {
+		handle.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// index reads the header, and the offsets of the lines following it, the
+// last one being the end of the file
+func (f *File) index() error {
+	reader := bufio.NewReader(f.handle)
+	line, err := reader.ReadBytes('\n')
+	if err != nil && err != io.EOF {
+		return err
+	}
+	if f.Header, err = readHeader(json.NewDecoder(bytes.NewReader(line))); err != nil {
+		return err
+	}
+	offset, inLine := int64(len(line)), false
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if len(chunk) > 0 && !inLine {
+			f.offsets = append(f.offsets, offset)
+		}
+		offset += int64(len(chunk))
+		switch err {
+		case nil:
+			inLine = false
+		case bufio.ErrBufferFull:
+			inLine = true
+		case io.EOF:
+			f.offsets = append(f.offsets, offset)
+			return nil
+		default:
+			return err
+		}
+	}
+}
+
+// Len implements Records
+func (f *File) Len() int {
+	return len(f.offsets) - 1
+}
+
+// Record implements Records, reading the record from the file
+func (f *File) Record(i int) (Record, error) {
+	line := make([]byte, f.offsets[i+1]-f.offsets[i])
+	if _, err := f.handle.ReadAt(line, f.offsets[i]); err != nil {
+		return Record{}, traceError{line: i + 2, err: err}
+	}
+	var record Record
+	if err := json.Unmarshal(line, &record); err != nil {
+		return Record{}, traceError{line: i + 2, err: err}
+	}
+	return record, nil
+}
+
+// Close closes the trace file
+func (f *File) Close() error {
+	return f.handle.Close()
+}
+
+// ReadFile loads the whole trace file
+func ReadFile(file string) (Trace, error) {
+	handle, err := os.Open(file)
+	if err != nil {
+		return Trace{}, traceOpenError{file: file, err: err}
+	}
+	defer handle.Close()
+	return Read(handle)
+}
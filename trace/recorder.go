@@ -0,0 +1,162 @@
+package trace
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"time"
+
+	"github.com/adrienlucbert/gofeur/logger"
+	"github.com/adrienlucbert/gofeur/parsing"
+	"github.com/adrienlucbert/gofeur/simulation"
+)
+
+// DefaultKeyframeInterval is the number of rounds between two keyframes of
+// the traces written by recorders, unless changed
+const DefaultKeyframeInterval = 100
+
+// Recorder is the application layer writing a trace of the simulation, in
+// JSON Lines: a header, then a record of the initial state, then a record per
+// round. It must be attached after the layer starting the simulation.
+type Recorder struct {
+	Scenario   *parsing.Simulation
+	Simulation *simulation.Simulation
+	// KeyframeInterval is the number of rounds between two keyframes, which
+	// must be set before the recorder is attached
+	KeyframeInterval uint
+
+	buffer   *bufio.Writer
+	encoder  *json.Encoder
+	closer   io.Closer
+	events   []simulation.Event
+	replayer replayer
+	state    State
+	err      error
+}
+
+// NewRecorder returns a recorder writing the trace of simulation, which input
+// is scenario, to w
+func NewRecorder(w io.Writer, scenario *parsing.Simulation, simulation *simulation.Simulation) *Recorder {
+	buffer := bufio.NewWriter(w)
+	return &Recorder{Scenario: scenario, Simulation: simulation, KeyframeInterval: DefaultKeyframeInterval, buffer: buffer, encoder: json.NewEncoder(buffer)}
+}
+
+type traceFileError struct {
+	file string
+	err  error
+}
+
+func (err traceFileError) Error() string {
+	return fmt.Sprintf("failed to create the trace file %s: %s", err.file, err.err.Error())
+}
+
+// Create returns a recorder writing the trace to file, which is created or
+// truncated
+func Create(file string, scenario *parsing.Simulation, simulation *simulation.Simulation) (*Recorder, error) {
+	handle, err := os.Create(file)
+	if err != nil {
+		return nil, traceFileError{file: file, err: err}
+	}
+	recorder := NewRecorder(handle, scenario, simulation)
+	recorder.closer = handle
+	return recorder, nil
+}
+
+// Attach writes the header of the trace and the initial state, and subscribes
+// to the simulation's events
+func (r *Recorder) Attach() {
+	var scenario bytes.Buffer
+	if r.err = parsing.Encode(&scenario, *r.Scenario, parsing.JSONFormat); r.err != nil {
+		return
+	}
+	if r.KeyframeInterval == 0 {
+		r.KeyframeInterval = DefaultKeyframeInterval
+	}
+	snapshot := r.Simulation.Snapshot()
+	r.write(Header{
+		FormatVersion:    FormatVersion,
+		Version:          Version(),
+		Width:            snapshot.Width,
+		Length:           snapshot.Length,
+		MaxRound:         snapshot.MaxRound,
+		KeyframeInterval: r.KeyframeInterval,
+		Scenario:         scenario.Bytes(),
+	})
+	r.replayer = newReplayer(r.Scenario)
+	r.state = stateOf(snapshot)
+	r.write(Record{State: &r.state})
+	r.Simulation.Subscribe(r)
+}
+
+// Update does nothing, as rounds are recorded as their events are emitted
+func (r *Recorder) Update(elapsedTime time.Duration) {}
+
+// Detach records the events of the round in progress, if any, and closes the
+// trace
+func (r *Recorder) Detach() {
+	if err := r.Close(); err != nil {
+		logger.Error("%s\n", err.Error())
+	}
+}
+
+// OnEvent implements simulation.Observer. A round is recorded once the next
+// one starts, or the simulation ends, so that its state includes what
+// happens after its last event, such as deadlock detection.
+func (r *Recorder) OnEvent(event simulation.Event) {
+	if event.Kind == simulation.RoundStartEvent && len(r.events) > 0 {
+		r.flushRound(false)
+	}
+	r.events = append(r.events, event)
+	if event.Kind == simulation.EndEvent {
+		r.flushRound(true)
+		if r.err == nil {
+			r.err = r.buffer.Flush()
+		}
+	}
+}
+
+// Close records the events of the round in progress, if any, flushes the
+// trace, closes its file, if any, and returns the first error met while
+// writing
+func (r *Recorder) Close() error {
+	if len(r.events) > 0 {
+		r.flushRound(true)
+	}
+	if r.err == nil {
+		r.err = r.buffer.Flush()
+	}
+	if r.closer != nil {
+		if err := r.closer.Close(); r.err == nil {
+			r.err = err
+		}
+		r.closer = nil
+	}
+	return r.err
+}
+
+// flushRound records the events of the round, and the state of the
+// simulation if keyframe is set, if it's time for a keyframe, or if the state
+// rebuilt from the events isn't the state of the simulation, such as when a
+// forklift fails to drop its parcel in a full truck
+func (r *Recorder) flushRound(keyframe bool) {
+	for _, event := range r.events {
+		r.replayer.apply(&r.state, event)
+	}
+	record := Record{Events: r.events}
+	state := stateOf(r.Simulation.Snapshot())
+	if keyframe || state.Round%r.KeyframeInterval == 0 || !reflect.DeepEqual(state, r.state) {
+		record.State, r.state = &state, state
+	}
+	r.write(record)
+	r.events = nil
+}
+
+func (r *Recorder) write(line any) {
+	if r.err == nil {
+		r.err = r.encoder.Encode(line)
+	}
+}
@@ -0,0 +1,157 @@
+package trace
+
+import (
+	"github.com/adrienlucbert/gofeur/parsing"
+	"github.com/adrienlucbert/gofeur/simulation"
+)
+
+// State is the state of the simulation held by keyframes. It leaves out what
+// the header holds, and what the strategy plans without showing it in events:
+// the paths of forklifts, their target unless they are grabbing a parcel, the
+// parcels they target before, and the load estimates of trucks.
+type State struct {
+	Round     uint                      `json:"round"`
+	Status    simulation.Status         `json:"status,omitempty"`
+	Parcels   []simulation.ParcelView   `json:"parcels"`
+	Forklifts []simulation.ForkliftView `json:"forklifts"`
+	Trucks    []simulation.TruckView    `json:"trucks"`
+}
+
+// stateOf returns the part of a snapshot that is recorded
+func stateOf(snapshot simulation.Snapshot) State {
+	state := State{Round: snapshot.Round, Status: snapshot.Status, Parcels: snapshot.Parcels, Forklifts: snapshot.Forklifts, Trucks: snapshot.Trucks}
+	grabbed := map[string]bool{}
+	for i := range state.Forklifts {
+		forklift := &state.Forklifts[i]
+		forklift.Path = nil
+		if forklift.Status == simulation.Grabbing {
+			grabbed[forklift.Target] = true
+		} else {
+			forklift.Target = ""
+		}
+	}
+	for i := range state.Parcels {
+		if parcel := &state.Parcels[i]; parcel.Status == simulation.Targeted && !grabbed[parcel.Name] {
+			parcel.Status = simulation.StandingBy
+		}
+	}
+	for i := range state.Trucks {
+		truck := &state.Trucks[i]
+		truck.LoadEstimate = 0
+		if len(truck.Trips) == 0 {
+			truck.Trips = nil
+		}
+	}
+	return state
+}
+
+// clone returns a copy of the state, which can be changed without affecting
+// the original
+func (state State) clone() State {
+	state.Parcels = append([]simulation.ParcelView(nil), state.Parcels...)
+	state.Forklifts = append([]simulation.ForkliftView(nil), state.Forklifts...)
+	state.Trucks = append([]simulation.TruckView(nil), state.Trucks...)
+	for i := range state.Trucks {
+		state.Trucks[i].Trips = append([]uint(nil), state.Trucks[i].Trips...)
+	}
+	return state
+}
+
+// snapshot returns the snapshot of the state, completed with the header
+func (state State) snapshot(header Header) simulation.Snapshot {
+	return simulation.Snapshot{
+		Round:     state.Round,
+		MaxRound:  header.MaxRound,
+		Status:    state.Status,
+		Width:     header.Width,
+		Length:    header.Length,
+		Parcels:   state.Parcels,
+		Forklifts: state.Forklifts,
+		Trucks:    state.Trucks,
+	}
+}
+
+// replayer rebuilds the states following a keyframe from the events of the
+// rounds, as the simulation of the scenario would have changed them
+type replayer struct {
+	parcels   map[string]int
+	forklifts map[string]int
+	trucks    map[string]int
+	awayTimes []uint
+}
+
+func newReplayer(scenario *parsing.Simulation) replayer {
+	r := replayer{parcels: map[string]int{}, forklifts: map[string]int{}, trucks: map[string]int{}}
+	for i, parcel := range scenario.Warehouse.Parcels {
+		r.parcels[parcel.Name] = i
+	}
+	for i, forklift := range scenario.Warehouse.Forklifts {
+		r.forklifts[forklift.Name] = i
+	}
+	for i, truck := range scenario.Warehouse.Trucks {
+		r.trucks[truck.Name] = i
+		r.awayTimes = append(r.awayTimes, uint(truck.Available))
+	}
+	return r
+}
+
+// apply changes the state as the event shows the simulation did. Events of
+// entities the scenario doesn't have are ignored.
+func (r replayer) apply(state *State, event simulation.Event) {
+	switch event.Kind {
+	case simulation.RoundStartEvent:
+		state.Round, state.Status = event.Round, simulation.Running
+	case simulation.WaitEvent, simulation.GoEvent, simulation.TakeEvent, simulation.LeaveEvent:
+		i, ok := r.forklifts[event.Entity]
+		if !ok || i >= len(state.Forklifts) {
+			return
+		}
+		forklift := &state.Forklifts[i]
+		r.finishAction(state, forklift)
+		forklift.Pos = event.Pos
+		switch event.Kind {
+		case simulation.TakeEvent:
+			forklift.Status, forklift.Target = simulation.Grabbing, event.Parcel
+			r.setParcelStatus(state, event.Parcel, simulation.Targeted)
+		case simulation.LeaveEvent:
+			forklift.Status = simulation.Dropping
+		}
+	case simulation.TruckWaitingEvent, simulation.TruckGoneEvent:
+		i, ok := r.trucks[event.Entity]
+		if !ok || i >= len(state.Trucks) {
+			return
+		}
+		truck := &state.Trucks[i]
+		if truck.Status == simulation.Away {
+			truck.AwayLeft--
+		} else if event.Kind == simulation.TruckGoneEvent {
+			truck.AwayLeft = r.awayTimes[i]
+			truck.Trips = append(truck.Trips, event.Load)
+		}
+		truck.Status, truck.Load = simulation.Loading, event.Load
+		if event.Kind == simulation.TruckGoneEvent {
+			truck.Status = simulation.Away
+		}
+	case simulation.EndEvent:
+		state.Round, state.Status = event.Round, event.Status
+	}
+}
+
+// finishAction completes the grabbing or dropping the forklift started the
+// round before, as forklifts do before acting
+func (r replayer) finishAction(state *State, forklift *simulation.ForkliftView) {
+	switch forklift.Status {
+	case simulation.Grabbing:
+		r.setParcelStatus(state, forklift.Target, simulation.Carried)
+		forklift.Parcel, forklift.Target, forklift.Status = forklift.Target, "", simulation.Loaded
+	case simulation.Dropping:
+		r.setParcelStatus(state, forklift.Parcel, simulation.DroppedOff)
+		forklift.Parcel, forklift.Status = "", simulation.Empty
+	}
+}
+
+func (r replayer) setParcelStatus(state *State, name string, status simulation.ParcelStatus) {
+	if i, ok := r.parcels[name]; ok && i < len(state.Parcels) {
+		state.Parcels[i].Status = status
+	}
+}
@@ -0,0 +1,64 @@
+// Package trace records runs of a simulation to trace files, and loads them
+// back, so that runs can be analysed or replayed without playing them again
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/adrienlucbert/gofeur/parsing"
+	"github.com/adrienlucbert/gofeur/simulation"
+)
+
+// FormatVersion is the version of the trace format written by Recorder. It is
+// increased whenever the format changes in a way older readers can't handle.
+const FormatVersion = 2
+
+// Header is the first line of a trace. Scenario is the simulation input, as a
+// JSON document, and Width, Length and MaxRound are the fixed fields of its
+// snapshots. A keyframe is recorded every KeyframeInterval rounds.
+type Header struct {
+	FormatVersion    int             `json:"format_version"`
+	Version          string          `json:"version"`
+	Width            uint            `json:"width"`
+	Length           uint            `json:"length"`
+	MaxRound         uint            `json:"max_round"`
+	KeyframeInterval uint            `json:"keyframe_interval"`
+	Scenario         json.RawMessage `json:"scenario"`
+}
+
+// Simulation parses the scenario of the trace
+func (header Header) Simulation() (parsing.Simulation, error) {
+	return parsing.ParseReader(bytes.NewReader(header.Scenario), parsing.JSONFormat)
+}
+
+// Record is a line of a trace following its header. Events are the events
+// emitted since the previous record, and State, for keyframes only, the state
+// of the simulation once they were emitted. The first record is a keyframe of
+// the state the simulation started from, with no event, and every following
+// one holds a round, so that records are numbered as rounds. The last record,
+// and those which state can't be rebuilt from the previous one and the
+// events, are keyframes too.
+type Record struct {
+	Events []simulation.Event `json:"events,omitempty"`
+	State  *State             `json:"state,omitempty"`
+}
+
+// Version returns the version of gofeur, as found in its build information
+func Version() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok || info.Main.Version == "" {
+		return "(devel)"
+	}
+	return info.Main.Version
+}
+
+type unsupportedFormatError struct {
+	version int
+}
+
+func (err unsupportedFormatError) Error() string {
+	return fmt.Sprintf("unsupported trace format version %d, expected %d", err.version, FormatVersion)
+}
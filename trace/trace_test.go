@@ -0,0 +1,261 @@
+package trace
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/adrienlucbert/gofeur/config"
+	"github.com/adrienlucbert/gofeur/parsing"
+	"github.com/adrienlucbert/gofeur/scenario"
+	"github.com/adrienlucbert/gofeur/simulation"
+	"github.com/stretchr/testify/assert"
+)
+
+type eventRecorder struct {
+	events []simulation.Event
+}
+
+func (r *eventRecorder) OnEvent(event simulation.Event) {
+	r.events = append(r.events, event)
+}
+
+func record(t *testing.T, gofeur *parsing.Simulation, strategy simulation.Strategy, interval uint) (Trace, []State, []simulation.Event) {
+	sim := simulation.New(gofeur, strategy)
+	sim.DeadlockRounds = simulation.SuggestedDeadlockRounds
+	live := &eventRecorder{}
+	sim.Subscribe(live)
+
+	var output bytes.Buffer
+	recorder := NewRecorder(&output, gofeur, &sim)
+	recorder.KeyframeInterval = interval
+	sim.Start()
+	recorder.Attach()
+	states := []State{stateOf(sim.Snapshot())}
+	for sim.IsRunning() {
+		// the last step may end the simulation without playing a round
+		if sim.Step(); sim.Round == uint(len(states)) {
+			states = append(states, stateOf(sim.Snapshot()))
+		} else {
+			states[len(states)-1] = stateOf(sim.Snapshot())
+		}
+	}
+	recorder.Detach()
+
+	lines := strings.Split(strings.TrimSuffix(output.String(), "\n"), "\n")
+	assert.Len(t, lines, len(states)+1)
+	trace, err := Read(&output)
+	assert.Nil(t, err)
+	return trace, states, live.events
+}
+
+func TestRecordAndRead(t *testing.T) {
+	config.Set("logLevel", "None")
+	gofeur, err := parsing.ParseInputFile("testdata/walls.txt")
+	assert.Nil(t, err)
+	trace, states, live := record(t, &gofeur, nil, 4)
+
+	assert.Equal(t, FormatVersion, trace.Header.FormatVersion)
+	assert.NotEmpty(t, trace.Header.Version)
+	assert.Equal(t, uint(7), trace.Header.Width)
+	assert.Equal(t, uint(5), trace.Header.Length)
+	assert.Equal(t, uint(200), trace.Header.MaxRound)
+	assert.Equal(t, uint(4), trace.Header.KeyframeInterval)
+	scenario, err := trace.Header.Simulation()
+	assert.Nil(t, err)
+	assert.Equal(t, gofeur, scenario)
+
+	assert.Len(t, trace.Records, len(states))
+	assert.Empty(t, trace.Records[0].Events)
+	assert.Equal(t, &states[0], trace.Records[0].State)
+	events := []simulation.Event{}
+	for i, record := range trace.Records[1:] {
+		assert.Equal(t, uint(i+1), record.Events[0].Round)
+		assert.Equal(t, simulation.RoundStartEvent, record.Events[0].Kind, "record %d", i+1)
+		assert.Equal(t, (i+1)%4 == 0 || i+1 == len(states)-1, record.State != nil, "record %d", i+1)
+		events = append(events, record.Events...)
+	}
+	assert.Equal(t, live, events)
+	assert.Equal(t, &states[len(states)-1], trace.Records[len(trace.Records)-1].State)
+	for _, forklift := range trace.Records[len(trace.Records)-1].State.Forklifts {
+		assert.Empty(t, forklift.Path)
+	}
+}
+
+func TestRecorderWritesUnexpectedStates(t *testing.T) {
+	config.Set("logLevel", "None")
+	gofeur, err := parsing.ParseInputFile("testdata/walls.txt")
+	assert.Nil(t, err)
+	sim := simulation.New(&gofeur, nil)
+	var output bytes.Buffer
+	recorder := NewRecorder(&output, &gofeur, &sim)
+	sim.Start()
+	recorder.Attach()
+	sim.Step()
+	sim.Step()
+	// as if the simulation changed the state in a way events don't show
+	recorder.state.Parcels[1].Status = simulation.DroppedOff
+	sim.Step()
+	sim.Step()
+	recorder.Detach()
+
+	trace, err := Read(&output)
+	assert.Nil(t, err)
+	assert.Len(t, trace.Records, 5)
+	assert.Nil(t, trace.Records[1].State)
+	assert.NotNil(t, trace.Records[2].State)
+	assert.Equal(t, simulation.StandingBy, trace.Records[2].State.Parcels[1].Status)
+	assert.Nil(t, trace.Records[3].State)
+	assert.NotNil(t, trace.Records[4].State)
+}
+
+func TestPlayerRebuildsStates(t *testing.T) {
+	config.Set("logLevel", "None")
+	for seed := int64(1); seed <= 5; seed++ {
+		params := scenario.DefaultParams()
+		params.Seed, params.Width, params.Length, params.Parcels, params.Forklifts = seed, 12, 12, 20, 3
+		gofeur, err := scenario.Generate(params)
+		assert.Nil(t, err)
+		for _, name := range []string{"greedy", "packing"} {
+			strategy, err := simulation.NewStrategy(name)
+			assert.Nil(t, err)
+			trace, states, _ := record(t, &gofeur, strategy, 10)
+			player, err := NewPlayer(trace.Header, trace)
+			assert.Nil(t, err)
+			assert.Equal(t, uint(len(states)-1), player.LastRound())
+			for round := range states {
+				assert.Equal(t, states[round].snapshot(trace.Header), player.Snapshot(), "seed %d, %s, round %d", seed, name, round)
+				player.Step()
+			}
+			for round := len(states) - 1; round >= 0; round -= 7 {
+				player.Seek(uint(round))
+				assert.Equal(t, states[round].snapshot(trace.Header), player.Snapshot(), "seed %d, %s, round %d", seed, name, round)
+				player.StepBack()
+				if round > 0 {
+					assert.Equal(t, states[round-1].snapshot(trace.Header), player.Snapshot(), "seed %d, %s, round %d", seed, name, round-1)
+				}
+			}
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	testCases := map[string]string{
+		"empty":               "",
+		"invalid header":      "{",
+		"older version":       `{"format_version":1,"version":"v1.0.0","scenario":{}}`,
+		"unsupported version": `{"format_version":3,"version":"v1.0.0","scenario":{}}`,
+		"invalid record":      "{\"format_version\":2,\"version\":\"v1.0.0\",\"scenario\":{}}\n{\"state\":[]}",
+		"invalid event kind":  "{\"format_version\":2,\"version\":\"v1.0.0\",\"scenario\":{}}\n{\"events\":[{\"kind\":\"fly\"}]}",
+	}
+	for name, input := range testCases {
+		_, err := Read(strings.NewReader(input))
+		assert.NotNil(t, err, name)
+	}
+
+	_, err := Read(strings.NewReader("{\"format_version\":2,\"version\":\"v1.0.0\",\"scenario\":{}}\n{\"state\":[]}"))
+	assert.True(t, strings.HasPrefix(err.Error(), "line 2 of the trace: "), err.Error())
+}
+
+func TestPlayer(t *testing.T) {
+	config.Set("logLevel", "None")
+	gofeur, err := parsing.ParseInputFile("testdata/walls.txt")
+	assert.Nil(t, err)
+	trace, states, _ := record(t, &gofeur, nil, 2)
+	trace.Records = trace.Records[:4]
+	player, err := NewPlayer(trace.Header, trace)
+	assert.Nil(t, err)
+	assert.Equal(t, uint(0), player.Round())
+	assert.Equal(t, uint(3), player.LastRound())
+	assert.Empty(t, player.Events())
+	assert.False(t, player.StepBack())
+
+	assert.True(t, player.Step())
+	assert.Equal(t, uint(1), player.Snapshot().Round)
+	assert.Equal(t, trace.Records[1].Events, player.Events())
+
+	player.Seek(3)
+	assert.Equal(t, uint(3), player.Round())
+	assert.Equal(t, states[3].snapshot(trace.Header), player.Snapshot())
+	assert.False(t, player.Step())
+	assert.True(t, player.StepBack())
+	assert.Equal(t, uint(2), player.Round())
+
+	player.Seek(100)
+	assert.Equal(t, uint(3), player.Round())
+	player.Seek(0)
+	assert.Equal(t, uint(0), player.Round())
+
+	// snapshots are copies
+	snapshot := player.Snapshot()
+	snapshot.Forklifts[0].Name = "changed"
+	assert.NotEqual(t, "changed", player.Snapshot().Forklifts[0].Name)
+
+	_, err = NewPlayer(trace.Header, Trace{})
+	assert.NotNil(t, err)
+	trace.Records[0].State = nil
+	_, err = NewPlayer(trace.Header, trace)
+	assert.NotNil(t, err)
+}
+
+func TestOpen(t *testing.T) {
+	config.Set("logLevel", "None")
+	gofeur, err := parsing.ParseInputFile("testdata/walls.txt")
+	assert.Nil(t, err)
+	recorded, states, _ := record(t, &gofeur, nil, 4)
+	// a record longer than the read buffer
+	recorded.Records[5].Events = append(recorded.Records[5].Events, simulation.Event{Round: 5, Kind: simulation.WaitEvent, Entity: strings.Repeat("a", 10000)})
+
+	file := filepath.Join(t.TempDir(), "run.jsonl")
+	write := func(records []Record, last string) {
+		var output bytes.Buffer
+		encoder := json.NewEncoder(&output)
+		assert.Nil(t, encoder.Encode(recorded.Header))
+		for _, record := range records {
+			assert.Nil(t, encoder.Encode(record))
+		}
+		output.WriteString(last)
+		assert.Nil(t, os.WriteFile(file, output.Bytes(), 0o644))
+	}
+	write(recorded.Records, "")
+	trace, err := Open(file)
+	assert.Nil(t, err)
+	assert.Equal(t, recorded.Header, trace.Header)
+	assert.Equal(t, len(recorded.Records), trace.Len())
+	for i := range recorded.Records {
+		record, err := trace.Record(i)
+		assert.Nil(t, err)
+		assert.Equal(t, recorded.Records[i], record)
+	}
+	player, err := NewPlayer(trace.Header, trace)
+	assert.Nil(t, err)
+	player.Seek(7)
+	assert.Equal(t, states[7].snapshot(trace.Header), player.Snapshot())
+	assert.Nil(t, trace.Close())
+
+	// the last line needn't end with a new line, and records are only
+	// decoded when they are read
+	write(recorded.Records[:9], "{")
+	trace, err = Open(file)
+	assert.Nil(t, err)
+	defer trace.Close()
+	assert.Equal(t, 10, trace.Len())
+	player, err = NewPlayer(trace.Header, trace)
+	assert.Nil(t, err)
+	player.Seek(8)
+	assert.Nil(t, player.Err())
+	assert.False(t, player.Step())
+	assert.True(t, strings.HasPrefix(player.Err().Error(), "line 11 of the trace: "), player.Err().Error())
+	assert.Equal(t, uint(8), player.Round())
+	player.Seek(2)
+	assert.Equal(t, uint(8), player.Round())
+
+	_, err = Open(filepath.Join(t.TempDir(), "missing.jsonl"))
+	assert.NotNil(t, err)
+	assert.Nil(t, os.WriteFile(file, nil, 0o644))
+	_, err = Open(file)
+	assert.Equal(t, errMissingHeader, err)
+}
@@ -6,17 +6,29 @@ import (
 
 	"github.com/adrienlucbert/gofeur/parsing"
 	"github.com/adrienlucbert/gofeur/simulation"
+	"github.com/adrienlucbert/gofeur/trace"
+	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
 
-// Layer is an optional application layer responsible for displaying the UI
+// Layer is an optional application layer responsible for displaying the UI,
+// of a live simulation, or of a recorded trace when Replay is set
 type Layer struct {
 	Gofeur     *parsing.Simulation
 	Simulation *simulation.Simulation
+	Replay     *trace.Player
 	ui         *UI
+	replay     replayState
+	// pending holds the events of the round, shown once it's over
+	pending []simulation.Event
+	// quit is closed when the user asks to quit, and done once the UI is
+	// stopped
+	quit chan struct{}
+	done chan struct{}
 }
 
 func (layer *Layer) run() {
+	defer close(layer.done)
 	err := layer.ui.App.SetRoot(layer.ui.Layout, true).
 		EnableMouse(true).
 		Run()
@@ -25,28 +37,93 @@ func (layer *Layer) run() {
 	}
 }
 
-// Attach initializes the UILayer
+// Attach initializes the UILayer, and subscribes it to the simulation events,
+// or sets up the replay controls
 func (layer *Layer) Attach() {
 	layer.ui = Start(layer.Gofeur)
+	layer.quit = make(chan struct{})
+	layer.done = make(chan struct{})
+	// the UI is stopped by Update, which then stops queueing updates: an
+	// update queued to a stopped UI would be waited for forever
+	layer.ui.App.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		if event.Key() == tcell.KeyEscape || event.Key() == tcell.KeyCtrlC {
+			select {
+			case <-layer.quit:
+			default:
+				close(layer.quit)
+			}
+			return nil
+		}
+		return event
+	})
+	if layer.Replay != nil {
+		layer.attachReplay()
+	} else {
+		layer.Simulation.Subscribe(layer)
+	}
 	go layer.run()
 }
 
+// IsRunning returns whether the UI is displayed, until it's quit
+func (layer *Layer) IsRunning() bool {
+	select {
+	case <-layer.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// isDisplayed stops the UI if the user asked to quit, and returns whether it
+// is still displayed, in which case updates can be queued
+func (layer *Layer) isDisplayed() bool {
+	select {
+	case <-layer.quit:
+		layer.ui.App.Stop()
+		return false
+	case <-layer.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// OnEvent keeps the actions of forklifts and trucks, to dump them in the
+// state box once the round is over
+func (layer *Layer) OnEvent(event simulation.Event) {
+	switch event.Kind {
+	case simulation.RoundStartEvent, simulation.RoundEndEvent, simulation.EndEvent:
+		return
+	}
+	layer.pending = append(layer.pending, event)
+}
+
 // Update updates the UI and re-renders it
 func (layer *Layer) Update(elapsedTime time.Duration) {
-	if layer.Simulation.Round%2 == 0 {
-		layer.ui.OutputBox.SetTitle("Go QUOI?")
-	} else {
-		layer.ui.OutputBox.SetTitle("Go FEUR...")
+	events := layer.pending
+	layer.pending = nil
+	if !layer.isDisplayed() {
+		return
+	}
+	if layer.Replay != nil {
+		layer.updateReplay(elapsedTime)
+		return
 	}
-	if !layer.ui.historic.IsRowSelected {
-		if layer.Simulation.Round == 2 {
-			layer.ui.DumpActionInStateBox(layer.Gofeur.Warehouse.Forklifts[0], "TEST")
+	round := layer.Simulation.Round
+	// widgets belong to the UI goroutine
+	layer.ui.App.QueueUpdateDraw(func() {
+		if round%2 == 0 {
+			layer.ui.OutputBox.SetTitle("Go QUOI?")
 		} else {
-			layer.ui.DumpActionInStateBox(layer.Gofeur.Warehouse.Trucks[0], "WAITING")
+			layer.ui.OutputBox.SetTitle("Go FEUR...")
 		}
-		layer.ui.OutputBox.SetCell(int(layer.Simulation.Round), 0, tview.NewTableCell(fmt.Sprintf("round %d\n", layer.Simulation.Round)))
-	}
-	layer.ui.App.Draw()
+		if !layer.ui.historic.IsRowSelected {
+			for _, event := range events {
+				layer.ui.DumpActionInStateBox(event.Entity, event.Action())
+			}
+			layer.ui.OutputBox.SetCell(int(round), 0, tview.NewTableCell(fmt.Sprintf("round %d\n", round)))
+		}
+	})
 }
 
 // Detach dismounts the UILayer
@@ -0,0 +1,178 @@
+package ui
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gdamore/tcell/v2"
+	"github.com/rivo/tview"
+)
+
+const (
+	// replayRoundDuration is how long each round is shown while playing
+	replayRoundDuration = 100 * time.Millisecond
+	// replaySeekRounds is the number of rounds skipped by page up and down
+	replaySeekRounds = 100
+)
+
+// replayControl is an action of the user on the replay, sent from the UI
+// goroutine to the one updating the layer
+type replayControl struct {
+	kind   replayControlKind
+	round  uint
+	offset int
+}
+
+type replayControlKind int
+
+const (
+	togglePlayControl replayControlKind = iota
+	stepControl
+	stepBackControl
+	seekControl
+	skipControl
+)
+
+// replayState is the state of the replay, only changed by Update
+type replayState struct {
+	controls chan replayControl
+	playing  bool
+	elapsed  time.Duration
+	drawn    uint
+	redraw   bool
+}
+
+// attachReplay lists the rounds of the trace in the output box, which select
+// the round shown, and binds the replay keys: space plays or pauses, n and p
+// step forwards and backwards, page down and up skip rounds, home and end go
+// to the first and last rounds
+func (layer *Layer) attachReplay() {
+	layer.replay = replayState{controls: make(chan replayControl, 16), redraw: true}
+	layer.ui.OutputBox.SetContent(roundList{rounds: int(layer.Replay.LastRound()) + 1})
+	layer.ui.OutputBox.SetSelectionChangedFunc(func(row, col int) {
+		layer.control(replayControl{kind: seekControl, round: uint(row)})
+	})
+
+	capture := layer.ui.Layout.GetInputCapture()
+	layer.ui.Layout.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
+		switch event.Key() {
+		case tcell.KeyPgDn:
+			layer.control(replayControl{kind: skipControl, offset: replaySeekRounds})
+		case tcell.KeyPgUp:
+			layer.control(replayControl{kind: skipControl, offset: -replaySeekRounds})
+		case tcell.KeyHome:
+			layer.control(replayControl{kind: seekControl, round: 0})
+		case tcell.KeyEnd:
+			layer.control(replayControl{kind: seekControl, round: layer.Replay.LastRound()})
+		case tcell.KeyRune:
+			switch event.Rune() {
+			case ' ':
+				layer.control(replayControl{kind: togglePlayControl})
+			case 'n':
+				layer.control(replayControl{kind: stepControl})
+			case 'p':
+				layer.control(replayControl{kind: stepBackControl})
+			default:
+				return capture(event)
+			}
+		default:
+			return capture(event)
+		}
+		return nil
+	})
+}
+
+// roundList is the content of the output box listing the rounds of the
+// replay, which cells are only created when they are drawn
+type roundList struct {
+	tview.TableContentReadOnly
+	rounds int
+}
+
+func (list roundList) GetCell(row, column int) *tview.TableCell {
+	if row < 0 || row >= list.rounds || column != 0 {
+		return nil
+	}
+	return tview.NewTableCell(fmt.Sprintf("round %d\n", row))
+}
+
+func (list roundList) GetRowCount() int {
+	return list.rounds
+}
+
+func (list roundList) GetColumnCount() int {
+	return 1
+}
+
+// control sends a control to Update, dropping it if too many are pending
+func (layer *Layer) control(control replayControl) {
+	select {
+	case layer.replay.controls <- control:
+	default:
+	}
+}
+
+// updateReplay applies the pending controls, plays a round if it's time to,
+// and redraws the UI if the round shown changed
+func (layer *Layer) updateReplay(elapsedTime time.Duration) {
+	state := &layer.replay
+	for pending := true; pending; {
+		select {
+		case control := <-state.controls:
+			layer.applyControl(control)
+		default:
+			pending = false
+		}
+	}
+	if state.playing {
+		state.elapsed += elapsedTime
+		for state.playing && state.elapsed >= replayRoundDuration {
+			state.elapsed -= replayRoundDuration
+			state.playing = layer.Replay.Step()
+		}
+	}
+	if layer.Replay.Err() != nil {
+		// the replay command returns the error once the UI is stopped
+		layer.ui.App.Stop()
+		return
+	}
+
+	round := layer.Replay.Round()
+	if round == state.drawn && !state.redraw {
+		return
+	}
+	state.drawn, state.redraw = round, false
+	snapshot, events, lastRound := layer.Replay.Snapshot(), layer.Replay.Events(), layer.Replay.LastRound()
+	layer.ui.App.QueueUpdateDraw(func() {
+		layer.ui.ShowSnapshot(snapshot)
+		layer.ui.StateBox.Clear()
+		for _, event := range events {
+			if event.Entity != "" {
+				fmt.Fprintf(layer.ui.StateBox, "%s %s\n", event.Entity, event.Action())
+			}
+		}
+		layer.ui.OutputBox.SetTitle(fmt.Sprintf("%d/%d", round, lastRound))
+	})
+}
+
+func (layer *Layer) applyControl(control replayControl) {
+	state := &layer.replay
+	switch control.kind {
+	case togglePlayControl:
+		state.playing, state.elapsed = !state.playing, 0
+	case stepControl:
+		state.playing = false
+		layer.Replay.Step()
+	case stepBackControl:
+		state.playing = false
+		layer.Replay.StepBack()
+	case seekControl:
+		layer.Replay.Seek(control.round)
+	case skipControl:
+		round := int(layer.Replay.Round()) + control.offset
+		if round < 0 {
+			round = 0
+		}
+		layer.Replay.Seek(uint(round))
+	}
+}
@@ -4,9 +4,11 @@ package ui
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 
 	"github.com/adrienlucbert/gofeur/parsing"
+	"github.com/adrienlucbert/gofeur/simulation"
 	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/tview"
 )
@@ -27,6 +29,7 @@ type UI struct {
 	Layout               *tview.Flex
 	building             [][]any
 	historic             history
+	warehouse            parsing.Warehouse
 }
 
 func addElementsToBuilding[T any](elements []T, building [][]any) {
@@ -43,11 +46,25 @@ func Start(st *parsing.Simulation) *UI {
 	app := tview.NewApplication()
 
 	ui := &UI{
-		App: app,
+		App:       app,
+		warehouse: st.Warehouse,
 	}
 
-	w := int(st.Warehouse.Width)
-	l := int(st.Warehouse.Length)
+	ui.resetBuilding()
+	addElementsToBuilding(st.Warehouse.Parcels, ui.building)
+	addElementsToBuilding(st.Warehouse.Forklifts, ui.building)
+	addElementsToBuilding(st.Warehouse.Trucks, ui.building)
+
+	ui.initUI()
+	ui.updateStorageBuildingTable(int(st.Warehouse.Width), int(st.Warehouse.Length))
+	ui.updateOutputBox()
+	return ui
+}
+
+// resetBuilding fills the building with empty tiles and obstacles
+func (ui *UI) resetBuilding() {
+	w := int(ui.warehouse.Width)
+	l := int(ui.warehouse.Length)
 
 	ui.building = make([][]any, l)
 
@@ -56,14 +73,37 @@ func Start(st *parsing.Simulation) *UI {
 			ui.building[y] = append(ui.building[y], ".")
 		}
 	}
-	addElementsToBuilding(st.Warehouse.Parcels, ui.building)
-	addElementsToBuilding(st.Warehouse.Forklifts, ui.building)
-	addElementsToBuilding(st.Warehouse.Trucks, ui.building)
+	for _, obstacle := range ui.warehouse.Obstacles {
+		for _, cell := range obstacle.Cells() {
+			ui.building[cell.Y][cell.X] = "#"
+		}
+	}
+}
 
-	ui.initUI()
-	ui.updateStorageBuildingTable(w, l)
-	ui.updateOutputBox()
-	return ui
+// ShowSnapshot redraws the building with the entities of a snapshot, parcels
+// carried or dropped off being hidden
+func (ui *UI) ShowSnapshot(snapshot simulation.Snapshot) {
+	ui.resetBuilding()
+	parcels := []parsing.Parcel{}
+	for i, view := range snapshot.Parcels {
+		if view.Status == simulation.Carried || view.Status == simulation.DroppedOff || i >= len(ui.warehouse.Parcels) {
+			continue
+		}
+		class, _ := ui.warehouse.ParcelClassOf(ui.warehouse.Parcels[i])
+		parcels = append(parcels, parsing.NewParcel(view.Name, uint32(view.Pos.X), uint32(view.Pos.Y), class))
+	}
+	forklifts := []parsing.Forklift{}
+	for _, view := range snapshot.Forklifts {
+		forklifts = append(forklifts, parsing.NewForklift(view.Name, uint32(view.Pos.X), uint32(view.Pos.Y)))
+	}
+	trucks := []parsing.Truck{}
+	for _, view := range snapshot.Trucks {
+		trucks = append(trucks, parsing.NewTruck(view.Name, uint32(view.Pos.X), uint32(view.Pos.Y), uint32(view.Capacity), 0))
+	}
+	addElementsToBuilding(parcels, ui.building)
+	addElementsToBuilding(forklifts, ui.building)
+	addElementsToBuilding(trucks, ui.building)
+	ui.updateStorageBuildingTable(int(ui.warehouse.Width), int(ui.warehouse.Length))
 }
 
 func (ui *UI) initUI() {
@@ -75,20 +115,14 @@ func (ui *UI) initUI() {
 	// truck state (WAITING, GONE), transpals actions (GO, WAIT, TAKE, LEAVE) etc...
 	infoBox := tview.NewTextView().
 		SetRegions(true).
-		SetScrollable(true).
-		SetChangedFunc(func() {
-			ui.App.Draw()
-		})
+		SetScrollable(true)
 	infoBox.
 		SetBorder(true).
 		SetTitle("Infos")
 
 	stateBox := tview.NewTextView().
 		SetRegions(true).
-		SetScrollable(true).
-		SetChangedFunc(func() {
-			ui.App.Draw()
-		})
+		SetScrollable(true)
 
 	stateBox.
 		SetBorder(true).
@@ -111,10 +145,6 @@ func (ui *UI) initUI() {
 				AddItem(infoBox, 0, 1, false).
 				AddItem(stateBox, 0, 1, false), 0, 1, false), 0, 5, false)
 	globalLayout.SetInputCapture(func(event *tcell.EventKey) *tcell.EventKey {
-		if event.Key() == tcell.KeyEscape {
-			ui.App.Stop()
-			return nil
-		}
 		if event.Key() == tcell.KeyRight {
 			ui.historic.IsRowSelected = false
 			ui.StateBox.Clear()
@@ -160,15 +190,32 @@ func (ui *UI) updateStorageBuildingTable(x, y int) {
 			fmt.Fprintf(ui.InfoBox, "x: %d, y: %d", col, row)
 			return
 		}
+		if ui.building[col][row] == "#" {
+			fmt.Fprintf(ui.InfoBox, "Obstacle, x: %d, y: %d", col, row)
+			return
+		}
 		va := reflect.ValueOf(ui.building[col][row])
 		name := va.FieldByName("Name").String()
 		fmt.Fprintf(ui.InfoBox, "Name: %s, x: %d, y: %d", name, col, row)
 	})
 	for row := 0; row < y; row++ {
 		for col := 0; col < x; col++ {
+			cellColor := color
+			text := fmt.Sprint(ui.building[row][col])
+			switch cell := ui.building[row][col].(type) {
+			case string:
+				if cell == "#" {
+					cellColor = tcell.ColorGray
+				}
+			case parsing.Parcel:
+				if class, ok := ui.warehouse.ParcelClassOf(cell); ok {
+					text = string(class.Glyph)
+					cellColor = parcelColor(class)
+				}
+			}
 			ui.StorageBuildingTable.SetCell(row, col,
-				tview.NewTableCell(fmt.Sprint(ui.building[row][col])).
-					SetTextColor(color).
+				tview.NewTableCell(text).
+					SetTextColor(cellColor).
 					SetExpansion(1).
 					SetAlign(tview.AlignCenter))
 		}
@@ -176,6 +223,15 @@ func (ui *UI) updateStorageBuildingTable(x, y int) {
 	ui.render()
 }
 
+// parcelColor returns the color a parcel class is displayed with, either a
+// color name or a rrggbb hexadecimal code
+func parcelColor(class parsing.ParcelClass) tcell.Color {
+	if code, err := strconv.ParseUint(class.Color, 16, 32); err == nil && len(class.Color) == 6 {
+		return tcell.NewHexColor(int32(code))
+	}
+	return tcell.GetColor(class.Color)
+}
+
 func (ui *UI) render() {
 	ui.App.ForceDraw()
 }